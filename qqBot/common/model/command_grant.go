@@ -0,0 +1,47 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CommandGrant 频道内的指令授权，授权给身份组或单个用户，RoleID 与 UserID 二选一
+type CommandGrant struct {
+	ID        uint   `gorm:"primaryKey"`
+	GuildID   string `gorm:"size:64;not null;index:idx_guild_command"`
+	Command   string `gorm:"size:64;not null;index:idx_guild_command"` // 指令名，如 /mute
+	RoleID    string `gorm:"size:64"`
+	UserID    string `gorm:"size:64"`
+	CreatedAt time.Time
+}
+
+// TableName 表名
+func (CommandGrant) TableName() string {
+	return "command_grant"
+}
+
+// Save 新增或更新授权
+func (g *CommandGrant) Save(db *gorm.DB) error {
+	return db.Save(g).Error
+}
+
+// CommandGrantStore 基于 MySQL 的指令授权存储
+type CommandGrantStore struct {
+	DB *gorm.DB
+}
+
+// Granted 判断用户或其身份组是否被授权在频道内使用指令
+func (s *CommandGrantStore) Granted(guildID, command, userID string, roles []string) (bool, error) {
+	query := s.DB.Model(&CommandGrant{}).Where("guild_id = ? AND command = ?", guildID, command)
+	if len(roles) > 0 {
+		query = query.Where(s.DB.Where("user_id = ?", userID).Or("role_id IN ?", roles))
+	} else {
+		query = query.Where("user_id = ?", userID)
+	}
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
@@ -0,0 +1,74 @@
+package model
+
+import (
+	"errors"
+	"qqbot/common/types"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 欢迎、告别消息模板中支持的占位符
+const (
+	PlaceholderNickname = "{nickname}" // 成员昵称，没有频道昵称时使用用户名
+	PlaceholderMention  = "{mention}"  // @成员
+)
+
+// WelcomeConfig 频道的欢迎、告别消息配置，模板为空时不发送对应的消息
+type WelcomeConfig struct {
+	GuildID          string `gorm:"primaryKey;size:64"`
+	ChannelID        string `gorm:"size:64;not null"` // 发送欢迎、告别消息的子频道
+	WelcomeTemplate  string `gorm:"size:1024"`
+	FarewellTemplate string `gorm:"size:1024"`
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+// TableName 表名
+func (WelcomeConfig) TableName() string {
+	return "welcome_config"
+}
+
+// GetWelcomeConfig 获取频道的欢迎消息配置，未配置时返回 nil
+func GetWelcomeConfig(db *gorm.DB, guildID string) (*WelcomeConfig, error) {
+	config := &WelcomeConfig{}
+	err := db.Where("guild_id = ?", guildID).Take(config).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Save 新增或更新配置
+func (c *WelcomeConfig) Save(db *gorm.DB) error {
+	return db.Save(c).Error
+}
+
+// WelcomeMessage 成员加入时发送的消息，未配置时返回空字符串
+func (c *WelcomeConfig) WelcomeMessage(member *types.Member) string {
+	return RenderMemberTemplate(c.WelcomeTemplate, member)
+}
+
+// FarewellMessage 成员退出时发送的消息，未配置时返回空字符串
+func (c *WelcomeConfig) FarewellMessage(member *types.Member) string {
+	return RenderMemberTemplate(c.FarewellTemplate, member)
+}
+
+// RenderMemberTemplate 替换模板中的成员占位符
+func RenderMemberTemplate(template string, member *types.Member) string {
+	var nickname, mention string
+	if member != nil {
+		nickname = member.Nick
+		if member.User != nil {
+			if nickname == "" {
+				nickname = member.User.Username
+			}
+			mention = "<@!" + member.User.ID + ">"
+		}
+	}
+	return strings.NewReplacer(PlaceholderNickname, nickname, PlaceholderMention, mention).Replace(template)
+}
@@ -0,0 +1,33 @@
+package model
+
+import (
+	"qqbot/common/types"
+	"testing"
+)
+
+func TestWelcomeConfig(t *testing.T) {
+	config := &WelcomeConfig{
+		WelcomeTemplate:  "欢迎 {mention} 加入，{nickname} 快来玩成语接龙吧",
+		FarewellTemplate: "",
+	}
+
+	t.Run("test placeholders replaced", func(t *testing.T) {
+		member := &types.Member{Nick: "小黑", User: &types.User{ID: "123", Username: "hei"}}
+		if msg := config.WelcomeMessage(member); msg != "欢迎 <@!123> 加入，小黑 快来玩成语接龙吧" {
+			t.Fatalf("unexpected message %q", msg)
+		}
+	})
+
+	t.Run("test username used without nick", func(t *testing.T) {
+		member := &types.Member{User: &types.User{ID: "123", Username: "hei"}}
+		if msg := RenderMemberTemplate("再见 {nickname}", member); msg != "再见 hei" {
+			t.Fatalf("unexpected message %q", msg)
+		}
+	})
+
+	t.Run("test empty template", func(t *testing.T) {
+		if msg := config.FarewellMessage(&types.Member{}); msg != "" {
+			t.Fatalf("unexpected message %q", msg)
+		}
+	})
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"qqbot/common/types"
+	"qqbot/constant"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// DeleteOption 撤回消息的可选参数
+type DeleteOption func(r *resty.Request)
+
+// WithHideTip 撤回后不显示「撤回了一条消息」的灰条提示，仅子频道和私信消息支持
+func WithHideTip() DeleteOption {
+	return func(r *resty.Request) {
+		r.SetQueryParam("hidetip", "true")
+	}
+}
+
+// DeleteMessage 撤回子频道消息
+func (client *HttpClient) DeleteMessage(ctx context.Context, channelID, messageID string, opts ...DeleteOption) error {
+	return client.deleteMessage(ctx, constant.MessageURI, map[string]string{
+		"channel_id": channelID,
+		"message_id": messageID,
+	}, opts)
+}
+
+// DeleteDirectMessage 撤回私信消息，guildID 为私信会话的频道ID
+func (client *HttpClient) DeleteDirectMessage(ctx context.Context, guildID, messageID string, opts ...DeleteOption) error {
+	return client.deleteMessage(ctx, constant.DMMessageURI, map[string]string{
+		"guild_id":   guildID,
+		"message_id": messageID,
+	}, opts)
+}
+
+// DeleteGroupMessage 撤回机器人发送的群聊消息
+func (client *HttpClient) DeleteGroupMessage(ctx context.Context, groupOpenID, messageID string) error {
+	return client.deleteMessage(ctx, constant.GroupMessageURI, map[string]string{
+		"group_openid": groupOpenID,
+		"message_id":   messageID,
+	}, nil)
+}
+
+// DeleteC2CMessage 撤回机器人发送的单聊消息
+func (client *HttpClient) DeleteC2CMessage(ctx context.Context, openID, messageID string) error {
+	return client.deleteMessage(ctx, constant.C2CMessageURI, map[string]string{
+		"openid":     openID,
+		"message_id": messageID,
+	}, nil)
+}
+
+func (client *HttpClient) deleteMessage(ctx context.Context, uri string, params map[string]string, opts []DeleteOption) error {
+	r := client.restyClient.R().SetContext(ctx).SetPathParams(params)
+	for _, opt := range opts {
+		opt(r)
+	}
+	_, err := r.Delete(uri)
+	return err
+}
+
+// MarkForDeletion 的错误
+var (
+	// errDeletionDisabled 标记了撤回但没有调用 EnableMessageDeletion
+	errDeletionDisabled = errors.New("message deletion is not enabled")
+	// ErrNotDeletable 事件不是正在分发的子频道或私信消息事件，群聊和单聊接口只能撤回机器人自己发送的消息
+	ErrNotDeletable = errors.New("message of this event can not be deleted")
+)
+
+var (
+	// deletionClient 撤回被标记的消息时使用的 client
+	deletionClient atomic.Pointer[HttpClient]
+	// deletionMarks 正在分发的可撤回消息事件，key 为 *types.WSPayload，value 为 *deletionMark，
+	// 只在事件分发期间存在，分发结束后移除
+	deletionMarks sync.Map
+)
+
+// deletionMark 一次事件分发中的撤回标记
+type deletionMark struct {
+	mu     sync.Mutex
+	marked bool
+	opts   []DeleteOption
+}
+
+// EnableMessageDeletion 设置撤回被标记消息时使用的 client，设置后 MarkForDeletion 才会生效
+func EnableMessageDeletion(client *HttpClient) {
+	deletionClient.Store(client)
+}
+
+// MarkForDeletion 在子频道或私信消息事件的 handler 中标记撤回收到的消息，如命中审核规则，
+// 所有 handler 执行完后统一撤回，多次标记只撤回一次，以最后一次的选项为准。
+// 只能在事件分发期间调用，其他事件或分发结束后调用返回 ErrNotDeletable
+func MarkForDeletion(event *types.WSPayload, opts ...DeleteOption) error {
+	if deletionClient.Load() == nil {
+		return errDeletionDisabled
+	}
+	v, ok := deletionMarks.Load(event)
+	if !ok {
+		return ErrNotDeletable
+	}
+	mark := v.(*deletionMark)
+	mark.mu.Lock()
+	defer mark.mu.Unlock()
+	mark.marked, mark.opts = true, opts
+	return nil
+}
+
+// publishDeletable 投递可以撤回的消息事件，所有 handler 执行完后撤回被标记的消息
+func publishDeletable(kind string, event *types.WSPayload, data *types.Message) error {
+	mark := &deletionMark{}
+	deletionMarks.Store(event, mark)
+	err := DefaultEventBus.Publish(kind, event, data)
+	deletionMarks.Delete(event)
+	return errors.Join(err, mark.delete(kind, data))
+}
+
+// delete 撤回被标记的消息
+func (m *deletionMark) delete(kind string, data *types.Message) error {
+	m.mu.Lock()
+	marked, opts := m.marked, m.opts
+	m.mu.Unlock()
+	if !marked {
+		return nil
+	}
+	client := deletionClient.Load()
+	if client == nil {
+		return errDeletionDisabled
+	}
+	ctx := context.Background()
+	var err error
+	switch kind {
+	case eventATMessage:
+		err = client.DeleteMessage(ctx, data.ChannelID, data.ID, opts...)
+	case eventDirectMessage:
+		err = client.DeleteDirectMessage(ctx, data.GuildID, data.ID, opts...)
+	default:
+		return fmt.Errorf("message deletion is not supported for %s", kind)
+	}
+	if err != nil {
+		return fmt.Errorf("delete marked message %s failed: %w", data.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"qqbot/common/service/servicetest"
+	"qqbot/common/types"
+	"testing"
+	"time"
+)
+
+func TestDeleteMessage(t *testing.T) {
+	ctx := context.Background()
+	server := servicetest.NewOpenAPIServer()
+	defer server.Close()
+	for _, path := range []string{"/channels/c1/messages/m1", "/dms/d1/messages/m1", "/v2/groups/g1/messages/m1", "/v2/users/u1/messages/m1"} {
+		server.Reply(http.MethodDelete, path, http.StatusOK, map[string]interface{}{})
+	}
+	client := NewClient(1024, "token", time.Second, WithBaseURL(server.URL))
+
+	t.Run("test delete apis", func(t *testing.T) {
+		if err := client.DeleteMessage(ctx, "c1", "m1", WithHideTip()); err != nil {
+			t.Fatalf("delete channel message failed: %v", err)
+		}
+		if hidetip := server.LastRequest().Query.Get("hidetip"); hidetip != "true" {
+			t.Fatalf("unexpected hidetip %q", hidetip)
+		}
+		if err := client.DeleteDirectMessage(ctx, "d1", "m1"); err != nil || server.LastRequest().Query.Has("hidetip") {
+			t.Fatalf("delete direct message failed: %v", err)
+		}
+		if err := client.DeleteGroupMessage(ctx, "g1", "m1"); err != nil {
+			t.Fatalf("delete group message failed: %v", err)
+		}
+		if err := client.DeleteC2CMessage(ctx, "u1", "m1"); err != nil {
+			t.Fatalf("delete c2c message failed: %v", err)
+		}
+	})
+
+	t.Run("test marked message deleted after handlers", func(t *testing.T) {
+		var moderation ATMessageEventHandler = func(event *types.WSPayload, data *types.Message) error {
+			if data.Content == "spam" {
+				return MarkForDeletion(event, WithHideTip())
+			}
+			return nil
+		}
+		sub, _ := DefaultEventBus.Subscribe(moderation, DefaultPriority)
+		defer sub.Unsubscribe()
+
+		EnableMessageDeletion(nil)
+		payload := &types.WSPayload{
+			WSPayloadBase: types.WSPayloadBase{Type: eventATMessage},
+			RawMessage:    []byte(`{"d":{"id":"m1","channel_id":"c1","content":"spam"}}`),
+		}
+		if err := ParseAndHandle(payload); !errors.Is(err, errDeletionDisabled) {
+			t.Fatalf("expect deletion disabled, got %v", err)
+		}
+
+		EnableMessageDeletion(client)
+		defer EnableMessageDeletion(nil)
+		before := len(server.Requests())
+		dispatch(t, eventATMessage, `{"id":"m1","channel_id":"c1","content":"hi"}`)
+		if len(server.Requests()) != before {
+			t.Fatalf("unmarked message should not be deleted")
+		}
+		dispatch(t, eventATMessage, `{"id":"m1","channel_id":"c1","content":"spam"}`)
+		req := server.LastRequest()
+		if len(server.Requests()) != before+1 || req.Path != "/channels/c1/messages/m1" || req.Query.Get("hidetip") != "true" {
+			t.Fatalf("marked message should be deleted, last request %+v", req)
+		}
+	})
+
+	t.Run("test marks rejected outside deletable dispatch", func(t *testing.T) {
+		EnableMessageDeletion(client)
+		defer EnableMessageDeletion(nil)
+		var markErr error
+		var group GroupATMessageEventHandler = func(event *types.WSPayload, data *types.Message) error {
+			markErr = MarkForDeletion(event)
+			return nil
+		}
+		sub, _ := DefaultEventBus.Subscribe(group, DefaultPriority)
+		defer sub.Unsubscribe()
+		before := len(server.Requests())
+		dispatch(t, eventGroupATMessage, `{"id":"m1","group_openid":"g1","content":"spam"}`)
+		if markErr != ErrNotDeletable || len(server.Requests()) != before {
+			t.Fatalf("group message should not be marked, got %v", markErr)
+		}
+
+		// 分发结束后不能再标记，也不会留下标记
+		var event *types.WSPayload
+		var at ATMessageEventHandler = func(e *types.WSPayload, data *types.Message) error {
+			event = e
+			return nil
+		}
+		sub, _ = DefaultEventBus.Subscribe(at, DefaultPriority)
+		defer sub.Unsubscribe()
+		dispatch(t, eventATMessage, `{"id":"m1","channel_id":"c1","content":"hi"}`)
+		if err := MarkForDeletion(event); err != ErrNotDeletable {
+			t.Fatalf("mark after dispatch should fail, got %v", err)
+		}
+		marks := 0
+		deletionMarks.Range(func(any, any) bool {
+			marks++
+			return true
+		})
+		if marks != 0 {
+			t.Fatalf("marks should be dropped after dispatch, %d left", marks)
+		}
+	})
+}
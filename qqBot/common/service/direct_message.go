@@ -0,0 +1,34 @@
+package service
+
+import (
+	"context"
+	"qqbot/common/types"
+	"qqbot/constant"
+)
+
+// CreateDirectMessage 创建私信会话，需要用户与机器人在同一个频道内
+func (client *HttpClient) CreateDirectMessage(ctx context.Context, dm *types.DirectMessageToCreate) (*types.DirectMessage, error) {
+	resp, err := client.restyClient.R().SetContext(ctx).
+		SetResult(types.DirectMessage{}).
+		SetBody(dm).
+		Post(constant.UserMeDMURI)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Result().(*types.DirectMessage), nil
+}
+
+// PostDirectMessage 在私信会话中发送消息
+func (client *HttpClient) PostDirectMessage(ctx context.Context, dm *types.DirectMessage, msg *types.MessageToCreate) (*types.Message, error) {
+	resp, err := client.restyClient.R().SetContext(ctx).
+		SetResult(types.Message{}).
+		SetPathParam("guild_id", dm.GuildID).
+		SetBody(v1Message(msg)).
+		Post(constant.DMsURI)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Result().(*types.Message), nil
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// HeaderTraceID OpenAPI 响应中用于排查问题的 trace id
+const HeaderTraceID = "X-Tps-trace-ID"
+
+// OpenAPI 业务错误码
+const (
+	CodeChannelMsgLimit  = 20028  // 子频道消息触发限频
+	CodeMsgLimitExceed   = 22009  // 消息发送超频
+	CodeMessageAuditing  = 304023 // 消息审核中，审核结果会通过 MESSAGE_AUDIT 事件通知
+	CodeMessageAuditing2 = 304024 // 消息审核中
+)
+
+// OpenAPIError OpenAPI 返回的非 2xx 响应，包含 QQ 的错误码、错误信息、HTTP 状态码和 trace id
+type OpenAPIError struct {
+	HTTPStatus int    `json:"-"`
+	Code       int    `json:"code"`
+	Message    string `json:"message"`
+	TraceID    string `json:"-"`
+}
+
+// Error 实现 error 接口
+func (e *OpenAPIError) Error() string {
+	return fmt.Sprintf("openapi error, status %d, code %d, message %s, trace id %s",
+		e.HTTPStatus, e.Code, e.Message, e.TraceID)
+}
+
+// checkResponse resty 的响应中间件，将非 2xx 的响应转换为 *OpenAPIError
+func checkResponse(_ *resty.Client, resp *resty.Response) error {
+	if resp.IsSuccess() {
+		return nil
+	}
+	return newOpenAPIError(resp)
+}
+
+// newOpenAPIError 从响应中解析错误信息，响应体不是 json 时直接使用原始内容作为错误信息
+func newOpenAPIError(resp *resty.Response) *OpenAPIError {
+	apiErr := &OpenAPIError{}
+	if err := json.Unmarshal(resp.Body(), apiErr); err != nil {
+		apiErr.Message = string(resp.Body())
+	}
+	apiErr.HTTPStatus = resp.StatusCode()
+	apiErr.TraceID = resp.Header().Get(HeaderTraceID)
+	return apiErr
+}
+
+// AsOpenAPIError 从 err 中取出 *OpenAPIError
+func AsOpenAPIError(err error) (*OpenAPIError, bool) {
+	var apiErr *OpenAPIError
+	ok := errors.As(err, &apiErr)
+	return apiErr, ok
+}
+
+// IsAuthError 鉴权失败，token 无效或过期
+func IsAuthError(err error) bool {
+	apiErr, ok := AsOpenAPIError(err)
+	return ok && apiErr.HTTPStatus == http.StatusUnauthorized
+}
+
+// IsPermissionError 机器人没有调用该接口或操作该资源的权限
+func IsPermissionError(err error) bool {
+	apiErr, ok := AsOpenAPIError(err)
+	return ok && apiErr.HTTPStatus == http.StatusForbidden
+}
+
+// IsRateLimited 触发了频率限制
+func IsRateLimited(err error) bool {
+	apiErr, ok := AsOpenAPIError(err)
+	if !ok {
+		return false
+	}
+	return apiErr.HTTPStatus == http.StatusTooManyRequests ||
+		apiErr.Code == CodeChannelMsgLimit || apiErr.Code == CodeMsgLimitExceed
+}
+
+// IsContentAuditError 消息需要经过内容审核，没有直接发送成功
+func IsContentAuditError(err error) bool {
+	apiErr, ok := AsOpenAPIError(err)
+	return ok && (apiErr.Code == CodeMessageAuditing || apiErr.Code == CodeMessageAuditing2)
+}
+
+// IsServerError OpenAPI 服务端错误
+func IsServerError(err error) bool {
+	apiErr, ok := AsOpenAPIError(err)
+	return ok && apiErr.HTTPStatus >= http.StatusInternalServerError
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"qqbot/common/types"
+	"sort"
+	"sync"
+)
+
+// DefaultPriority RegisterHandlers 注册的 handler 使用的优先级
+const DefaultPriority = 0
+
+// ErrStopPropagation handler 返回该错误时，不再调用后续优先级更低的 handler
+var ErrStopPropagation = errors.New("stop propagation")
+
+// EventHandlerFunc 统一签名的事件处理函数，data 为解析后的事件数据，如 *types.Message
+type EventHandlerFunc func(event *types.WSPayload, data interface{}) error
+
+// Subscription 一个事件订阅，通过 Unsubscribe 取消
+type Subscription struct {
+	bus      *EventBus
+	kind     string
+	id       uint64
+	priority int
+	handler  EventHandlerFunc
+}
+
+// Unsubscribe 取消订阅，可以重复调用
+func (s *Subscription) Unsubscribe() {
+	s.bus.unsubscribe(s)
+}
+
+// EventBus 事件总线，同一类事件可以有任意多个订阅者，按优先级从高到低依次调用，
+// 优先级相同时按订阅的先后顺序调用
+type EventBus struct {
+	mu          sync.RWMutex
+	subs        map[string][]*Subscription
+	nextID      uint64
+	middlewares []Middleware
+}
+
+// DefaultEventBus ParseAndHandle 解析出的事件都会发布到这里，默认使用 Recovery，
+// 单个 handler 的 panic 不会断开 websocket 连接
+var DefaultEventBus = func() *EventBus {
+	bus := NewEventBus()
+	bus.Use(Recovery())
+	return bus
+}()
+
+// NewEventBus 创建事件总线
+func NewEventBus() *EventBus {
+	return &EventBus{subs: make(map[string][]*Subscription)}
+}
+
+// Subscribe 订阅事件，handler 为 ATMessageEventHandler 等事件回调类型或 WithMiddleware 的返回值，
+// 事件种类由 handler 的类型决定，priority 越大越先执行
+func (b *EventBus) Subscribe(handler interface{}, priority int) (*Subscription, error) {
+	kind, _, fn, ok := handlerInfo(handler)
+	if !ok {
+		return nil, fmt.Errorf("unsupported event handler type %T", handler)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nextID++
+	sub := &Subscription{bus: b, kind: kind, id: b.nextID, priority: priority, handler: fn}
+	// 复制后再修改，保证 Publish 中拿到的订阅列表不会被并发修改
+	subs := append(append([]*Subscription(nil), b.subs[kind]...), sub)
+	sort.SliceStable(subs, func(i, j int) bool {
+		return subs[i].priority > subs[j].priority
+	})
+	b.subs[kind] = subs
+	return sub, nil
+}
+
+// Use 注册全局中间件，对所有 handler 生效，包括之前订阅的 handler，先注册的中间件在外层
+func (b *EventBus) Use(mws ...Middleware) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.middlewares = append(append([]Middleware(nil), b.middlewares...), mws...)
+}
+
+// unsubscribe 从订阅列表中移除 sub
+func (b *EventBus) unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	subs := b.subs[sub.kind]
+	for i, s := range subs {
+		if s.id == sub.id {
+			b.subs[sub.kind] = append(append([]*Subscription(nil), subs[:i]...), subs[i+1:]...)
+			return
+		}
+	}
+}
+
+// Publish 将事件依次投递给订阅者，某个 handler 返回 ErrStopPropagation 时停止投递，
+// 其他错误不影响后续 handler 的执行，所有错误合并后返回
+func (b *EventBus) Publish(kind string, event *types.WSPayload, data interface{}) error {
+	b.mu.RLock()
+	subs := b.subs[kind]
+	chain := Chain(b.middlewares...)
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, sub := range subs {
+		if err := chain(sub.handler)(event, data); err != nil {
+			if errors.Is(err, ErrStopPropagation) {
+				break
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// HandlerIntent 返回 handler 对应事件需要订阅的 intent，不支持的 handler 返回 0
+func HandlerIntent(handler interface{}) int {
+	_, intent, _, _ := handlerInfo(handler)
+	return intent
+}
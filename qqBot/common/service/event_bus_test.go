@@ -0,0 +1,83 @@
+package service
+
+import (
+	"errors"
+	"qqbot/common/types"
+	"qqbot/constant"
+	"reflect"
+	"testing"
+)
+
+func TestEventBus(t *testing.T) {
+	payload := &types.WSPayload{}
+	data := &types.Message{Content: "hi"}
+
+	t.Run("test handlers called by priority", func(t *testing.T) {
+		bus := NewEventBus()
+		var calls []string
+		handler := func(name string) ATMessageEventHandler {
+			return func(event *types.WSPayload, data *types.Message) error {
+				calls = append(calls, name)
+				return nil
+			}
+		}
+		_, _ = bus.Subscribe(handler("low"), -1)
+		_, _ = bus.Subscribe(handler("game"), DefaultPriority)
+		_, _ = bus.Subscribe(handler("chat"), DefaultPriority)
+		_, _ = bus.Subscribe(handler("high"), 10)
+		if err := bus.Publish(eventATMessage, payload, data); err != nil {
+			t.Fatalf("publish failed: %v", err)
+		}
+		if !reflect.DeepEqual(calls, []string{"high", "game", "chat", "low"}) {
+			t.Fatalf("unexpected call order %v", calls)
+		}
+	})
+
+	t.Run("test stop propagation and errors", func(t *testing.T) {
+		bus := NewEventBus()
+		errFailed := errors.New("failed")
+		var called bool
+		var failed ATMessageEventHandler = func(*types.WSPayload, *types.Message) error { return errFailed }
+		var stop ATMessageEventHandler = func(*types.WSPayload, *types.Message) error { return ErrStopPropagation }
+		var after ATMessageEventHandler = func(*types.WSPayload, *types.Message) error {
+			called = true
+			return nil
+		}
+		_, _ = bus.Subscribe(failed, 3)
+		_, _ = bus.Subscribe(stop, 2)
+		_, _ = bus.Subscribe(after, 1)
+		if err := bus.Publish(eventATMessage, payload, data); !errors.Is(err, errFailed) {
+			t.Fatalf("handler error should be returned, got %v", err)
+		}
+		if called {
+			t.Fatalf("handler after stop propagation should not be called")
+		}
+	})
+
+	t.Run("test unsubscribe", func(t *testing.T) {
+		bus := NewEventBus()
+		var count int
+		var handler ATMessageEventHandler = func(*types.WSPayload, *types.Message) error {
+			count++
+			return nil
+		}
+		sub, _ := bus.Subscribe(handler, DefaultPriority)
+		_ = bus.Publish(eventATMessage, payload, data)
+		sub.Unsubscribe()
+		sub.Unsubscribe()
+		_ = bus.Publish(eventATMessage, payload, data)
+		if count != 1 {
+			t.Fatalf("unsubscribed handler should not be called, called %d times", count)
+		}
+	})
+
+	t.Run("test intents of registered handlers", func(t *testing.T) {
+		var atMessage ATMessageEventHandler = func(*types.WSPayload, *types.Message) error { return nil }
+		if HandlerIntent(atMessage) != constant.IntentGuildAtMessages {
+			t.Fatalf("unexpected intent %d", HandlerIntent(atMessage))
+		}
+		if _, err := NewEventBus().Subscribe(func() {}, DefaultPriority); err == nil {
+			t.Fatalf("unsupported handler should fail")
+		}
+	})
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"qqbot/common/types"
+	"qqbot/constant"
+	"sync"
+	"time"
+)
+
+// msgSeqTTL 被动回复的有效期，超过后不会再回复同一条消息，序号可以丢弃
+const msgSeqTTL = 5 * time.Minute
+
+// PostGroupMessage 发送群聊消息，回复消息时未指定 MsgSeq 会按 MsgID/EventID 自动递增
+func (client *HttpClient) PostGroupMessage(ctx context.Context, groupOpenID string, msg *types.MessageToCreate) (*types.MessageResponse, error) {
+	resp, err := client.restyClient.R().SetContext(ctx).
+		SetResult(types.MessageResponse{}).
+		SetPathParam("group_openid", groupOpenID).
+		SetBody(client.withMsgSeq(msg)).
+		Post(constant.GroupMessagesURI)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Result().(*types.MessageResponse), nil
+}
+
+// PostC2CMessage 发送单聊消息，openid 为用户在机器人下的 openid
+func (client *HttpClient) PostC2CMessage(ctx context.Context, openID string, msg *types.MessageToCreate) (*types.MessageResponse, error) {
+	resp, err := client.restyClient.R().SetContext(ctx).
+		SetResult(types.MessageResponse{}).
+		SetPathParam("openid", openID).
+		SetBody(client.withMsgSeq(msg)).
+		Post(constant.C2CMessagesURI)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Result().(*types.MessageResponse), nil
+}
+
+// v1Message 去掉只有群聊和单聊(v2)接口支持的 msg_type 和 msg_seq，返回副本，不修改调用方的消息，
+// 用于子频道和私信接口，MessageBuilder 构建的消息可以直接用于两类接口
+func v1Message(msg *types.MessageToCreate) *types.MessageToCreate {
+	if msg == nil || (msg.MsgType == types.MsgTypeText && msg.MsgSeq == 0) {
+		return msg
+	}
+	m := *msg
+	m.MsgType, m.MsgSeq = types.MsgTypeText, 0
+	return &m
+}
+
+// withMsgSeq 为被动回复补充 msg_seq，返回副本，不修改调用方的消息
+func (client *HttpClient) withMsgSeq(msg *types.MessageToCreate) *types.MessageToCreate {
+	replyTo := msg.MsgID
+	if replyTo == "" {
+		replyTo = msg.EventID
+	}
+	if msg.MsgSeq != 0 || replyTo == "" {
+		return msg
+	}
+	m := *msg
+	m.MsgSeq = client.msgSeq.next(replyTo)
+	return &m
+}
+
+type msgSeqEntry struct {
+	seq    uint32
+	expire time.Time
+}
+
+// msgSeqTracker 记录每条被回复消息已经使用的 msg_seq，相同的 msg_id + msg_seq 会被服务端去重
+type msgSeqTracker struct {
+	mu        sync.Mutex
+	entries   map[string]*msgSeqEntry
+	lastPrune time.Time
+}
+
+func newMsgSeqTracker() *msgSeqTracker {
+	return &msgSeqTracker{entries: make(map[string]*msgSeqEntry)}
+}
+
+// next 返回回复 msgID 时使用的下一个序号，从 1 开始
+func (t *msgSeqTracker) next(msgID string) uint32 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	if now.Sub(t.lastPrune) > time.Minute {
+		for id, e := range t.entries {
+			if now.After(e.expire) {
+				delete(t.entries, id)
+			}
+		}
+		t.lastPrune = now
+	}
+	e, ok := t.entries[msgID]
+	if !ok {
+		e = &msgSeqEntry{}
+		t.entries[msgID] = e
+	}
+	e.seq++
+	e.expire = now.Add(msgSeqTTL)
+	return e.seq
+}
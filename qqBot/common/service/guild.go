@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"qqbot/common/types"
+	"qqbot/constant"
+	"strconv"
+)
+
+// errGuildPager before 和 after 不能同时指定
+var errGuildPager = errors.New("guild pager: before and after can not be set together")
+
+// Me 获取机器人的用户信息
+func (client *HttpClient) Me(ctx context.Context) (*types.User, error) {
+	resp, err := client.restyClient.R().SetContext(ctx).
+		SetResult(types.User{}).
+		Get(constant.UserMeURI)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Result().(*types.User), nil
+}
+
+// MeGuilds 分页获取机器人所在的频道列表，pager 为 nil 时拉取第一页
+func (client *HttpClient) MeGuilds(ctx context.Context, pager *types.GuildPager) ([]*types.Guild, error) {
+	r := client.restyClient.R().SetContext(ctx)
+	if pager != nil {
+		if pager.Before != "" && pager.After != "" {
+			return nil, errGuildPager
+		}
+		if pager.Before != "" {
+			r.SetQueryParam("before", pager.Before)
+		}
+		if pager.After != "" {
+			r.SetQueryParam("after", pager.After)
+		}
+		if pager.Limit > 0 {
+			r.SetQueryParam("limit", strconv.Itoa(pager.Limit))
+		}
+	}
+	var guilds []*types.Guild
+	if _, err := r.SetResult(&guilds).Get(constant.UserMeGuildsURI); err != nil {
+		return nil, err
+	}
+
+	return guilds, nil
+}
+
+// Guild 获取频道详情
+func (client *HttpClient) Guild(ctx context.Context, guildID string) (*types.Guild, error) {
+	resp, err := client.restyClient.R().SetContext(ctx).
+		SetResult(types.Guild{}).
+		SetPathParam("guild_id", guildID).
+		Get(constant.GuildURI)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Result().(*types.Guild), nil
+}
+
+// Channels 获取频道下的子频道列表
+func (client *HttpClient) Channels(ctx context.Context, guildID string) ([]*types.Channel, error) {
+	var channels []*types.Channel
+	_, err := client.restyClient.R().SetContext(ctx).
+		SetResult(&channels).
+		SetPathParam("guild_id", guildID).
+		Get(constant.ChannelsURI)
+	if err != nil {
+		return nil, err
+	}
+
+	return channels, nil
+}
+
+// Channel 获取子频道详情
+func (client *HttpClient) Channel(ctx context.Context, channelID string) (*types.Channel, error) {
+	resp, err := client.restyClient.R().SetContext(ctx).
+		SetResult(types.Channel{}).
+		SetPathParam("channel_id", channelID).
+		Get(constant.ChannelURI)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Result().(*types.Channel), nil
+}
+
+// GuildMembers 分页获取频道成员列表，返回的成员数小于 Limit 时说明已经拉取完成，
+// 下一页的 After 为本页最后一个成员的用户ID
+func (client *HttpClient) GuildMembers(ctx context.Context, guildID string, pager *types.GuildMembersPager) ([]*types.Member, error) {
+	after, limit := "0", 1
+	if pager != nil {
+		if pager.After != "" {
+			after = pager.After
+		}
+		if pager.Limit > 0 {
+			limit = pager.Limit
+		}
+	}
+	var members []*types.Member
+	_, err := client.restyClient.R().SetContext(ctx).
+		SetResult(&members).
+		SetPathParam("guild_id", guildID).
+		SetQueryParam("after", after).
+		SetQueryParam("limit", strconv.Itoa(limit)).
+		Get(constant.GuildMembersURI)
+	if err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+// Roles 获取频道身份组列表
+func (client *HttpClient) Roles(ctx context.Context, guildID string) (*types.GuildRoles, error) {
+	resp, err := client.restyClient.R().SetContext(ctx).
+		SetResult(types.GuildRoles{}).
+		SetPathParam("guild_id", guildID).
+		Get(constant.RolesURI)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Result().(*types.GuildRoles), nil
+}
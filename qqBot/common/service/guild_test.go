@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"qqbot/common/service/servicetest"
+	"qqbot/common/types"
+	"testing"
+	"time"
+)
+
+func TestGuildQuery(t *testing.T) {
+	ctx := context.Background()
+	server := servicetest.NewOpenAPIServer()
+	defer server.Close()
+	server.Reply(http.MethodGet, "/users/@me", http.StatusOK, &types.User{ID: "bot", Bot: true})
+	server.Reply(http.MethodGet, "/users/@me/guilds", http.StatusOK, []*types.Guild{{ID: "g1"}, {ID: "g2"}})
+	server.Reply(http.MethodGet, "/guilds/g1", http.StatusOK, &types.Guild{ID: "g1", MemberCount: 3})
+	server.Reply(http.MethodGet, "/guilds/g1/channels", http.StatusOK, []*types.Channel{{ID: "c1", Type: types.ChannelTypeText}})
+	server.Reply(http.MethodGet, "/channels/c1", http.StatusOK, &types.Channel{ID: "c1", GuildID: "g1"})
+	server.Reply(http.MethodGet, "/guilds/g1/members", http.StatusOK, []*types.Member{{User: &types.User{ID: "u1"}, Roles: []string{"4"}}})
+	server.Reply(http.MethodGet, "/guilds/g1/roles", http.StatusOK, &types.GuildRoles{GuildID: "g1", Roles: []*types.Role{{ID: "4", Name: "频道主"}}})
+	client := NewClient(1024, "token", time.Second, WithBaseURL(server.URL))
+
+	t.Run("test me and guilds", func(t *testing.T) {
+		me, err := client.Me(ctx)
+		if err != nil || me.ID != "bot" || !me.Bot {
+			t.Fatalf("unexpected me %+v, err %v", me, err)
+		}
+		guilds, err := client.MeGuilds(ctx, &types.GuildPager{After: "g0", Limit: 2})
+		if err != nil || len(guilds) != 2 || guilds[1].ID != "g2" {
+			t.Fatalf("unexpected guilds %+v, err %v", guilds, err)
+		}
+		if query := server.LastRequest().Query; query.Get("after") != "g0" || query.Get("limit") != "2" || query.Has("before") {
+			t.Fatalf("unexpected query %v", query)
+		}
+		if _, err = client.MeGuilds(ctx, &types.GuildPager{Before: "a", After: "b"}); !errors.Is(err, errGuildPager) {
+			t.Fatalf("expect pager error, got %v", err)
+		}
+		guild, err := client.Guild(ctx, "g1")
+		if err != nil || guild.MemberCount != 3 {
+			t.Fatalf("unexpected guild %+v, err %v", guild, err)
+		}
+	})
+
+	t.Run("test channels", func(t *testing.T) {
+		channels, err := client.Channels(ctx, "g1")
+		if err != nil || len(channels) != 1 || channels[0].ID != "c1" {
+			t.Fatalf("unexpected channels %+v, err %v", channels, err)
+		}
+		channel, err := client.Channel(ctx, "c1")
+		if err != nil || channel.GuildID != "g1" {
+			t.Fatalf("unexpected channel %+v, err %v", channel, err)
+		}
+	})
+
+	t.Run("test members and roles", func(t *testing.T) {
+		members, err := client.GuildMembers(ctx, "g1", nil)
+		if err != nil || len(members) != 1 || members[0].User.ID != "u1" {
+			t.Fatalf("unexpected members %+v, err %v", members, err)
+		}
+		if query := server.LastRequest().Query; query.Get("after") != "0" || query.Get("limit") != "1" {
+			t.Fatalf("unexpected query %v", query)
+		}
+		if _, err = client.GuildMembers(ctx, "g1", &types.GuildMembersPager{After: "u1", Limit: 400}); err != nil {
+			t.Fatalf("get members failed: %v", err)
+		}
+		if query := server.LastRequest().Query; query.Get("after") != "u1" || query.Get("limit") != "400" {
+			t.Fatalf("unexpected query %v", query)
+		}
+		roles, err := client.Roles(ctx, "g1")
+		if err != nil || len(roles.Roles) != 1 || roles.Roles[0].Name != "频道主" {
+			t.Fatalf("unexpected roles %+v, err %v", roles, err)
+		}
+	})
+}
@@ -1,40 +1,111 @@
 package service
 
 import (
-	"fmt"
 	"github.com/go-resty/resty/v2"
 	"log"
 	"net"
 	"net/http"
+	"qqbot/constant"
+	"strings"
 	"time"
 )
 
 type HttpClient struct {
 	AppID       uint64
 	AccessToken string
-	tokenType   string
 	timeout     time.Duration
+	baseURL     string        // OpenAPI 域名，默认为正式环境
+	tokenSource TokenSource   // 接口调用凭证来源，默认使用 Bot {appid}.{token} 静态 token
 	restyClient *resty.Client // resty client 复用
+
+	limiter      *routeLimiter // 按 route + 子频道区分的客户端限频，为 nil 时不限频
+	retryCount   int
+	retryWait    time.Duration
+	retryMaxWait time.Duration
+
+	msgSeq *msgSeqTracker // 群聊和单聊被动回复的 msg_seq
+}
+
+// ClientOption HttpClient 的可选配置
+type ClientOption func(client *HttpClient)
+
+// WithTokenSource 指定接口调用凭证来源，如 AppAccessTokenSource
+func WithTokenSource(tokenSource TokenSource) ClientOption {
+	return func(client *HttpClient) {
+		client.tokenSource = tokenSource
+	}
+}
+
+// WithSandbox 使用沙箱环境的 OpenAPI
+func WithSandbox() ClientOption {
+	return WithBaseURL(constant.SandboxDomain)
+}
+
+// WithBaseURL 使用自定义的 OpenAPI 地址，如集成测试中的模拟服务
+func WithBaseURL(baseURL string) ClientOption {
+	return func(client *HttpClient) {
+		client.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
 }
 
 // NewClient 函数: 创建一个新的 HttpClient 实例
-func NewClient(ID uint64, token string, duration time.Duration) *HttpClient {
+func NewClient(ID uint64, token string, duration time.Duration, opts ...ClientOption) *HttpClient {
 	client := &HttpClient{
 		AppID:       ID,
 		AccessToken: token,
 		timeout:     duration,
-		tokenType:   "Bot",
+		baseURL:     constant.ProductionDomain,
+		tokenSource: &Token{AppID: ID, AccessToken: token, Type: TokenTypeBot},
+
+		limiter:      newRouteLimiter(defaultRateLimit, defaultRateBurst),
+		retryCount:   defaultRetryCount,
+		retryWait:    defaultRetryWait,
+		retryMaxWait: defaultRetryMaxWait,
+
+		msgSeq: newMsgSeqTracker(),
+	}
+	for _, opt := range opts {
+		opt(client)
 	}
 
 	client.restyClient = resty.New().
 		SetTransport(newTransport(nil, 1000)). // 自定义 transport
 		SetTimeout(client.timeout).
-		SetAuthToken(fmt.Sprintf("%v.%s", client.AppID, client.AccessToken)).
-		SetAuthScheme(client.tokenType)
+		SetBaseURL(client.baseURL).
+		SetRetryCount(client.retryCount).
+		SetRetryWaitTime(client.retryWait).
+		SetRetryMaxWaitTime(client.retryMaxWait).
+		SetRetryAfter(retryAfter).
+		AddRetryCondition(shouldRetry).
+		OnBeforeRequest(client.waitRateLimit). // 限频需要在路径参数替换前计算 route
+		OnBeforeRequest(client.setAuthorization).
+		OnAfterResponse(client.observeRateLimit). // 需要在 checkResponse 之前记录限频信息
+		OnAfterResponse(checkResponse)            // 非 2xx 的响应转换为 *OpenAPIError
 
 	return client
 }
 
+// BaseURL 返回 HttpClient 使用的 OpenAPI 地址
+func (client *HttpClient) BaseURL() string {
+	return client.baseURL
+}
+
+// TokenSource 返回 HttpClient 使用的凭证来源，websocket 鉴权时复用
+func (client *HttpClient) TokenSource() TokenSource {
+	return client.tokenSource
+}
+
+// setAuthorization 每次请求前从凭证来源获取 token，设置鉴权头
+func (client *HttpClient) setAuthorization(_ *resty.Client, r *resty.Request) error {
+	token, err := client.tokenSource.Token(r.Context())
+	if err != nil {
+		return err
+	}
+	r.SetAuthScheme(token.Type)
+	r.SetAuthToken(token.ToStr())
+	return nil
+}
+
 // newTransport 创建一个自定义的 http.Transport 实例
 func newTransport(localAddr net.Addr, maxIdleConns int) *http.Transport {
 	dialer := &net.Dialer{
@@ -60,21 +131,27 @@ func newTransport(localAddr net.Addr, maxIdleConns int) *http.Transport {
 
 // newConnect 建立新的 WebSocket 连接,并处理连接成功或失败的情况
 func (l *ChanManager) newConnect(session Session) {
+	wsClient := NewWebsocket(session)
 	defer func() {
 		// panic 留下日志，放回 session
 		if err := recover(); err != nil {
-			PanicHandler(err, &session)
-			l.sessionChan <- session
+			currentSession := wsClient.GetSession()
+			PanicHandler(err, currentSession)
+			l.sessionChan <- *currentSession
 		}
 	}()
 
-	wsClient := NewWebsocket(session)
 	if err := wsClient.Connect(); err != nil {
 		log.Println(err)
 		l.sessionChan <- session // 连接失败，丢回去队列排队重连
 		return
 	}
+	// 记录当前 shard 的连接，用于监控网关时延
+	shardID := session.Shards.ShardID
+	l.clients.Store(shardID, wsClient)
+	defer l.clients.CompareAndDelete(shardID, wsClient)
 
+	// 有 session ID 时通过 resume 续传事件，否则重新鉴权
 	var err error
 	if session.ID != "" {
 		err = wsClient.ReTry()
@@ -83,12 +160,25 @@ func (l *ChanManager) newConnect(session Session) {
 	}
 	if err != nil {
 		log.Printf("[ws/session] Identify/Resume err %+v", err)
+		wsClient.Close()
+		l.sessionChan <- session // 发送鉴权数据失败，丢回去队列排队重连
 		return
 	}
 
 	if err = wsClient.Listening(); err != nil {
 		log.Printf("[ws/session] Listening err %+v", err)
 		currentSession := wsClient.GetSession()
+		// 对于不能够续传的 session，需要清空 session id 与 seq，下次连接重新鉴权
+		if CanNotResume(err) {
+			currentSession.ID = ""
+			currentSession.LastSeq = 0
+		}
+		// 机器人被下架或封禁时无法再鉴权，不再重连
+		if CanNotIdentify(err) {
+			log.Printf("[ws/session] %s can not identify because server return %+v, stop reconnecting",
+				currentSession, err)
+			return
+		}
 		l.sessionChan <- *currentSession
 		return
 	}
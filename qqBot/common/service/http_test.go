@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"qqbot/common/service/servicetest"
+	"qqbot/common/types"
+	"qqbot/constant"
+	"testing"
+	"time"
+)
+
+func TestHttpClient(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("test base url", func(t *testing.T) {
+		if NewClient(1, "t", time.Second).BaseURL() != constant.ProductionDomain {
+			t.Fatalf("default base url should be production")
+		}
+		if NewClient(1, "t", time.Second, WithSandbox()).BaseURL() != constant.SandboxDomain {
+			t.Fatalf("sandbox base url not applied")
+		}
+	})
+
+	t.Run("test requests sent to custom base url", func(t *testing.T) {
+		server := servicetest.NewOpenAPIServer()
+		defer server.Close()
+		server.Reply(http.MethodGet, "/gateway/bot", http.StatusOK, &types.WebsocketAP{URL: "wss://fake", Shards: 1})
+		server.Reply(http.MethodPost, "/channels/100/messages", http.StatusOK, &types.Message{ID: "m1"})
+
+		client := NewClient(1024, "token", time.Second, WithBaseURL(server.URL+"/"))
+		ap, err := client.GetWSS(ctx)
+		if err != nil || ap.URL != "wss://fake" {
+			t.Fatalf("unexpected gateway %+v, err %v", ap, err)
+		}
+		if auth := server.LastRequest().Header.Get("Authorization"); auth != "Bot 1024.token" {
+			t.Fatalf("unexpected authorization %s", auth)
+		}
+		msg, err := client.PostMessage(ctx, "100", &types.MessageToCreate{Content: "hi"})
+		if err != nil || msg.ID != "m1" {
+			t.Fatalf("unexpected message %+v, err %v", msg, err)
+		}
+	})
+
+	t.Run("test direct message", func(t *testing.T) {
+		server := servicetest.NewOpenAPIServer()
+		defer server.Close()
+		server.Reply(http.MethodPost, "/users/@me/dms", http.StatusOK, &types.DirectMessage{GuildID: "dm1", ChannelID: "c1"})
+		server.Reply(http.MethodPost, "/dms/dm1/messages", http.StatusOK, &types.Message{ID: "m1"})
+		client := NewClient(1024, "token", time.Second, WithBaseURL(server.URL))
+
+		dm, err := client.CreateDirectMessage(ctx, &types.DirectMessageToCreate{SourceGuildID: "g1", RecipientID: "u1"})
+		if err != nil || dm.GuildID != "dm1" {
+			t.Fatalf("unexpected dm %+v, err %v", dm, err)
+		}
+		if body := string(server.LastRequest().Body); body != `{"source_guild_id":"g1","recipient_id":"u1"}` {
+			t.Fatalf("unexpected body %s", body)
+		}
+		// 子频道和私信接口不支持 msg_type
+		msg, err := client.PostDirectMessage(ctx, dm, &types.MessageToCreate{Markdown: &types.Markdown{Content: "hi"}, MsgType: types.MsgTypeMarkdown})
+		if err != nil || msg.ID != "m1" {
+			t.Fatalf("unexpected message %+v, err %v", msg, err)
+		}
+		if body := string(server.LastRequest().Body); body != `{"markdown":{"content":"hi"}}` {
+			t.Fatalf("unexpected body %s", body)
+		}
+	})
+
+	t.Run("test group and c2c message", func(t *testing.T) {
+		server := servicetest.NewOpenAPIServer()
+		defer server.Close()
+		server.Reply(http.MethodPost, "/v2/groups/g1/messages", http.StatusOK, map[string]interface{}{"id": "m1", "timestamp": 1725442341})
+		server.Reply(http.MethodPost, "/v2/users/u1/messages", http.StatusOK, map[string]interface{}{"id": "m2", "timestamp": "2024-09-04T17:32:21+08:00"})
+		client := NewClient(1024, "token", time.Second, WithBaseURL(server.URL))
+
+		reply := &types.MessageToCreate{Content: "hi", MsgID: "src"}
+		for _, want := range []string{
+			`{"content":"hi","msg_id":"src","msg_seq":1}`,
+			`{"content":"hi","msg_id":"src","msg_seq":2}`,
+		} {
+			msg, err := client.PostGroupMessage(ctx, "g1", reply)
+			if err != nil || msg.ID != "m1" {
+				t.Fatalf("unexpected message %+v, err %v", msg, err)
+			}
+			if body := string(server.LastRequest().Body); body != want {
+				t.Fatalf("unexpected body %s", body)
+			}
+		}
+		if reply.MsgSeq != 0 {
+			t.Fatalf("caller's message should not be modified")
+		}
+
+		msg, err := client.PostC2CMessage(ctx, "u1", &types.MessageToCreate{Content: "hi", MsgSeq: 5, MsgID: "src"})
+		if err != nil || msg.ID != "m2" {
+			t.Fatalf("unexpected message %+v, err %v", msg, err)
+		}
+		if body := string(server.LastRequest().Body); body != `{"content":"hi","msg_id":"src","msg_seq":5}` {
+			t.Fatalf("unexpected body %s", body)
+		}
+	})
+
+	t.Run("test typed openapi errors", func(t *testing.T) {
+		server := servicetest.NewOpenAPIServer()
+		defer server.Close()
+		client := NewClient(1024, "token", time.Second, WithBaseURL(server.URL))
+		reply := func(status int, body string) {
+			server.Handle(http.MethodPost, "/channels/100/messages", func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set(HeaderTraceID, "trace")
+				w.WriteHeader(status)
+				_, _ = w.Write([]byte(body))
+			})
+		}
+
+		reply(http.StatusUnauthorized, `{"code":11241,"message":"wrong token"}`)
+		_, err := client.PostMessage(ctx, "100", &types.MessageToCreate{Content: "hi"})
+		apiErr, ok := AsOpenAPIError(err)
+		if !ok || !IsAuthError(err) || apiErr.Code != 11241 || apiErr.TraceID != "trace" {
+			t.Fatalf("unexpected error %v", err)
+		}
+
+		reply(http.StatusForbidden, `{"code":11264,"message":"no permission"}`)
+		if _, err = client.PostMessage(ctx, "100", &types.MessageToCreate{}); !IsPermissionError(err) || IsAuthError(err) {
+			t.Fatalf("expect permission error, got %v", err)
+		}
+
+		reply(http.StatusBadRequest, `{"code":22009,"message":"msg limit exceed"}`)
+		if _, err = client.PostMessage(ctx, "100", &types.MessageToCreate{}); !IsRateLimited(err) {
+			t.Fatalf("expect rate limited, got %v", err)
+		}
+
+		reply(http.StatusBadRequest, `{"code":304023,"message":"push message is auditing"}`)
+		if _, err = client.PostMessage(ctx, "100", &types.MessageToCreate{}); !IsContentAuditError(err) {
+			t.Fatalf("expect content audit error, got %v", err)
+		}
+
+		reply(http.StatusBadGateway, `bad gateway`)
+		_, err = client.PostMessage(ctx, "100", &types.MessageToCreate{})
+		if apiErr, ok = AsOpenAPIError(err); !ok || !IsServerError(err) || apiErr.Message != "bad gateway" {
+			t.Fatalf("expect server error, got %v", err)
+		}
+	})
+
+	t.Run("test access token used for authorization", func(t *testing.T) {
+		tokenServer := servicetest.NewTokenServer(1024, "secret", 7200)
+		defer tokenServer.Close()
+		server := servicetest.NewOpenAPIServer()
+		defer server.Close()
+		server.Reply(http.MethodGet, "/gateway/bot", http.StatusOK, &types.WebsocketAP{})
+
+		client := NewClient(1024, "", time.Second, WithBaseURL(server.URL),
+			WithTokenSource(NewAppAccessTokenSource(1024, "secret", tokenServer.URL)))
+		if _, err := client.GetWSS(ctx); err != nil {
+			t.Fatalf("get gateway failed: %v", err)
+		}
+		if auth := server.LastRequest().Header.Get("Authorization"); auth != "QQBot "+tokenServer.TokenFor(1) {
+			t.Fatalf("unexpected authorization %s", auth)
+		}
+	})
+}
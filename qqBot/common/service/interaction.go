@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"log"
+	"qqbot/common/types"
+	"qqbot/constant"
+	"sync"
+)
+
+// PutInteraction 回应互动事件，收到 INTERACTION_CREATE 后需要及时回应，否则客户端会一直显示加载中
+func (client *HttpClient) PutInteraction(ctx context.Context, interactionID string, code types.InteractionCode) error {
+	_, err := client.restyClient.R().SetContext(ctx).
+		SetPathParam("interaction_id", interactionID).
+		SetBody(map[string]types.InteractionCode{"code": code}).
+		Put(constant.InteractionURI)
+	return err
+}
+
+// ButtonCallback 按钮回调，在回应互动事件之后调用，回调中可以发送消息等耗时操作
+type ButtonCallback func(interaction *types.Interaction)
+
+// ButtonRouter 按按钮ID将按钮回调分发给注册的 ButtonCallback，并回应互动事件
+type ButtonRouter struct {
+	client    *HttpClient
+	mu        sync.RWMutex
+	callbacks map[string]ButtonCallback
+}
+
+// NewButtonRouter 创建按钮路由，client 用于回应互动事件
+func NewButtonRouter(client *HttpClient) *ButtonRouter {
+	return &ButtonRouter{client: client, callbacks: make(map[string]ButtonCallback)}
+}
+
+// Handle 注册按钮回调，相同ID的回调会被覆盖
+func (r *ButtonRouter) Handle(buttonID string, callback ButtonCallback) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.callbacks[buttonID] = callback
+}
+
+// Remove 移除按钮回调
+func (r *ButtonRouter) Remove(buttonID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.callbacks, buttonID)
+}
+
+// HandleInteraction 先回应互动事件再调用按钮回调，避免回调耗时导致客户端显示操作失败，
+// 可以作为 InteractionEventHandler 注册，没有注册回调的按钮回应操作失败
+func (r *ButtonRouter) HandleInteraction(event *types.WSPayload, data *types.Interaction) error {
+	var callback ButtonCallback
+	if data.Data != nil && data.Data.Resolved != nil {
+		r.mu.RLock()
+		callback = r.callbacks[data.Data.Resolved.ButtonID]
+		r.mu.RUnlock()
+		if callback == nil {
+			log.Printf("[interaction] no callback for button %s", data.Data.Resolved.ButtonID)
+		}
+	}
+	if callback == nil {
+		return r.client.PutInteraction(context.Background(), data.ID, types.InteractionCodeFailed)
+	}
+	if err := r.client.PutInteraction(context.Background(), data.ID, types.InteractionCodeSuccess); err != nil {
+		log.Printf("[interaction] ack %s failed, %v", data.ID, err)
+	}
+	callback(data)
+	return nil
+}
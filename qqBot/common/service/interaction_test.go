@@ -0,0 +1,43 @@
+package service
+
+import (
+	"net/http"
+	"qqbot/common/service/servicetest"
+	"qqbot/common/types"
+	"testing"
+	"time"
+)
+
+func TestButtonRouter(t *testing.T) {
+	server := servicetest.NewOpenAPIServer()
+	defer server.Close()
+	server.Reply(http.MethodPut, "/interactions/i1", http.StatusNoContent, nil)
+	router := NewButtonRouter(NewClient(1024, "token", time.Second, WithBaseURL(server.URL)))
+	sub, _ := DefaultEventBus.Subscribe(InteractionEventHandler(router.HandleInteraction), DefaultPriority)
+	defer sub.Unsubscribe()
+
+	var clicked *types.Interaction
+	var ackedFirst bool
+	router.Handle("hint", func(interaction *types.Interaction) {
+		clicked = interaction
+		ackedFirst = len(server.Requests()) > 0 && string(server.LastRequest().Body) == `{"code":0}`
+	})
+
+	t.Run("test button routed by id and acked", func(t *testing.T) {
+		dispatch(t, eventInteraction, `{"id":"i1","type":11,"chat_type":0,"guild_id":"g1","channel_id":"c1",`+
+			`"data":{"type":11,"resolved":{"button_id":"hint","button_data":"hint","user_id":"u1"}}}`)
+		if clicked == nil || clicked.ChannelID != "c1" || clicked.Data.Resolved.UserID != "u1" {
+			t.Fatalf("unexpected interaction %+v", clicked)
+		}
+		if !ackedFirst {
+			t.Fatalf("interaction should be acked before the callback runs")
+		}
+	})
+
+	t.Run("test unknown button acked as failed", func(t *testing.T) {
+		dispatch(t, eventInteraction, `{"id":"i1","data":{"type":11,"resolved":{"button_id":"unknown"}}}`)
+		if body := string(server.LastRequest().Body); body != `{"code":1}` {
+			t.Fatalf("unexpected ack %s", body)
+		}
+	})
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"qqbot/common/types"
+	"qqbot/constant"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// PostMessageWithImage 以 multipart 方式发送带本地图片的子频道消息，image 可以是内存中生成的图片
+func (client *HttpClient) PostMessageWithImage(ctx context.Context, channelID string, msg *types.MessageToCreate,
+	fileName string, image io.Reader) (*types.Message, error) {
+	r, err := client.multipartRequest(ctx, msg, fileName, image)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.SetPathParam("channel_id", channelID).Post(constant.MessagesURI)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Result().(*types.Message), nil
+}
+
+// PostDirectMessageWithImage 以 multipart 方式在私信会话中发送带本地图片的消息
+func (client *HttpClient) PostDirectMessageWithImage(ctx context.Context, dm *types.DirectMessage, msg *types.MessageToCreate,
+	fileName string, image io.Reader) (*types.Message, error) {
+	r, err := client.multipartRequest(ctx, msg, fileName, image)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.SetPathParam("guild_id", dm.GuildID).Post(constant.DMsURI)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Result().(*types.Message), nil
+}
+
+// UploadGroupMedia 上传群聊富媒体文件，返回的 FileInfo 用于发送富媒体消息
+func (client *HttpClient) UploadGroupMedia(ctx context.Context, groupOpenID string, fileType types.FileType, file io.Reader) (*types.Media, error) {
+	return client.uploadMedia(ctx, constant.GroupFilesURI, "group_openid", groupOpenID, fileType, file)
+}
+
+// UploadC2CMedia 上传单聊富媒体文件，返回的 FileInfo 用于发送富媒体消息
+func (client *HttpClient) UploadC2CMedia(ctx context.Context, openID string, fileType types.FileType, file io.Reader) (*types.Media, error) {
+	return client.uploadMedia(ctx, constant.C2CFilesURI, "openid", openID, fileType, file)
+}
+
+// PostGroupMedia 上传文件后发送群聊富媒体消息，msg 可以为 nil，用于指定回复的消息和文字内容
+func (client *HttpClient) PostGroupMedia(ctx context.Context, groupOpenID string, msg *types.MessageToCreate,
+	fileType types.FileType, file io.Reader) (*types.MessageResponse, error) {
+	media, err := client.UploadGroupMedia(ctx, groupOpenID, fileType, file)
+	if err != nil {
+		return nil, err
+	}
+	return client.PostGroupMessage(ctx, groupOpenID, mediaMessage(msg, media))
+}
+
+// PostC2CMedia 上传文件后发送单聊富媒体消息，msg 可以为 nil，用于指定回复的消息和文字内容
+func (client *HttpClient) PostC2CMedia(ctx context.Context, openID string, msg *types.MessageToCreate,
+	fileType types.FileType, file io.Reader) (*types.MessageResponse, error) {
+	media, err := client.UploadC2CMedia(ctx, openID, fileType, file)
+	if err != nil {
+		return nil, err
+	}
+	return client.PostC2CMessage(ctx, openID, mediaMessage(msg, media))
+}
+
+// uploadMedia 将文件以 base64 编码上传
+func (client *HttpClient) uploadMedia(ctx context.Context, uri, param, id string, fileType types.FileType, file io.Reader) (*types.Media, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.restyClient.R().SetContext(ctx).
+		SetResult(types.Media{}).
+		SetPathParam(param, id).
+		SetBody(&types.MediaToUpload{FileType: fileType, FileData: base64.StdEncoding.EncodeToString(data)}).
+		Post(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Result().(*types.Media), nil
+}
+
+// mediaMessage 返回带有 media 的消息副本
+func mediaMessage(msg *types.MessageToCreate, media *types.Media) *types.MessageToCreate {
+	m := types.MessageToCreate{}
+	if msg != nil {
+		m = *msg
+	}
+	m.MsgType = types.MsgTypeMedia
+	m.Media = &types.MessageMedia{FileInfo: media.FileInfo}
+	return &m
+}
+
+// multipartRequest 构造 file_image 的 multipart 请求，请求体提前读入内存，重试时可以重复发送
+func (client *HttpClient) multipartRequest(ctx context.Context, msg *types.MessageToCreate, fileName string, image io.Reader) (*resty.Request, error) {
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	fields, err := messageFormFields(msg)
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range fields {
+		if err = w.WriteField(field[0], field[1]); err != nil {
+			return nil, err
+		}
+	}
+	part, err := w.CreateFormFile("file_image", fileName)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = io.Copy(part, image); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	return client.restyClient.R().SetContext(ctx).
+		SetResult(types.Message{}).
+		SetHeader("Content-Type", w.FormDataContentType()).
+		SetBody(body.Bytes()), nil
+}
+
+// messageFormFields 将消息转换为 multipart 表单字段，结构化的字段以 json 字符串发送
+func messageFormFields(msg *types.MessageToCreate) ([][2]string, error) {
+	if msg == nil {
+		return nil, nil
+	}
+	var fields [][2]string
+	for _, f := range [][2]string{{"content", msg.Content}, {"msg_id", msg.MsgID}, {"event_id", msg.EventID}, {"image", msg.Image}} {
+		if f[1] != "" {
+			fields = append(fields, f)
+		}
+	}
+	structured := []struct {
+		name  string
+		value interface{}
+		set   bool
+	}{
+		{"embed", msg.Embed, msg.Embed != nil},
+		{"ark", msg.Ark, msg.Ark != nil},
+		{"markdown", msg.Markdown, msg.Markdown != nil},
+		{"keyboard", msg.Keyboard, msg.Keyboard != nil},
+		{"message_reference", msg.MessageReference, msg.MessageReference != nil},
+	}
+	for _, f := range structured {
+		if !f.set {
+			continue
+		}
+		data, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, [2]string{f.name, string(data)})
+	}
+	return fields, nil
+}
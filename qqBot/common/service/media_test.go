@@ -0,0 +1,74 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"qqbot/common/service/servicetest"
+	"qqbot/common/types"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMedia(t *testing.T) {
+	ctx := context.Background()
+	image := []byte("\x89PNG fake image")
+
+	t.Run("test file image sent as multipart", func(t *testing.T) {
+		server := servicetest.NewOpenAPIServer()
+		defer server.Close()
+		server.Reply(http.MethodPost, "/channels/c1/messages", http.StatusOK, &types.Message{ID: "m1"})
+		client := NewClient(1024, "token", time.Second, WithBaseURL(server.URL))
+
+		msg, err := client.PostMessageWithImage(ctx, "c1", &types.MessageToCreate{
+			Content: "排行榜", MsgID: "src", MessageReference: &types.MessageReference{MessageID: "src"},
+		}, "rank.png", bytes.NewReader(image))
+		if err != nil || msg.ID != "m1" {
+			t.Fatalf("unexpected message %+v, err %v", msg, err)
+		}
+
+		req := server.LastRequest()
+		_, params, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+		form, err := multipart.NewReader(bytes.NewReader(req.Body), params["boundary"]).ReadForm(1 << 20)
+		if err != nil {
+			t.Fatalf("parse multipart failed: %v", err)
+		}
+		if form.Value["content"][0] != "排行榜" || form.Value["msg_id"][0] != "src" ||
+			form.Value["message_reference"][0] != `{"message_id":"src"}` {
+			t.Fatalf("unexpected fields %v", form.Value)
+		}
+		file, _ := form.File["file_image"][0].Open()
+		data, _ := io.ReadAll(file)
+		if form.File["file_image"][0].Filename != "rank.png" || !bytes.Equal(data, image) {
+			t.Fatalf("unexpected file %q", data)
+		}
+	})
+
+	t.Run("test group media uploaded then sent", func(t *testing.T) {
+		server := servicetest.NewOpenAPIServer()
+		defer server.Close()
+		server.Reply(http.MethodPost, "/v2/groups/g1/files", http.StatusOK, &types.Media{FileUUID: "uuid", FileInfo: "info", TTL: 3600})
+		server.Reply(http.MethodPost, "/v2/groups/g1/messages", http.StatusOK, &types.MessageResponse{ID: "m1"})
+		client := NewClient(1024, "token", time.Second, WithBaseURL(server.URL))
+
+		msg, err := client.PostGroupMedia(ctx, "g1", &types.MessageToCreate{MsgID: "src"}, types.FileTypeImage, strings.NewReader(string(image)))
+		if err != nil || msg.ID != "m1" {
+			t.Fatalf("unexpected message %+v, err %v", msg, err)
+		}
+		requests := server.Requests()
+		upload := &types.MediaToUpload{}
+		_ = json.Unmarshal(requests[0].Body, upload)
+		if upload.FileType != types.FileTypeImage || upload.FileData != base64.StdEncoding.EncodeToString(image) || upload.SrvSendMsg {
+			t.Fatalf("unexpected upload %s", requests[0].Body)
+		}
+		if body := string(requests[1].Body); body != `{"media":{"file_info":"info"},"msg_id":"src","msg_type":7,"msg_seq":1}` {
+			t.Fatalf("unexpected message body %s", body)
+		}
+	})
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"qqbot/common/types"
+	"runtime"
+	"time"
+)
+
+// ErrHandlerPanic Recovery 捕获到 handler panic 时返回的错误
+var ErrHandlerPanic = errors.New("handler panic")
+
+// Middleware 事件处理中间件，包装 next 并返回新的处理函数，可以在调用 next 前后执行逻辑，或者不调用 next 直接跳过
+type Middleware func(next EventHandlerFunc) EventHandlerFunc
+
+// Chain 将多个中间件组合为一个，第一个中间件在最外层
+func Chain(mws ...Middleware) Middleware {
+	return func(next EventHandlerFunc) EventHandlerFunc {
+		for i := len(mws) - 1; i >= 0; i-- {
+			next = mws[i](next)
+		}
+		return next
+	}
+}
+
+// middlewareHandler 附带中间件的 handler，由 WithMiddleware 创建
+type middlewareHandler struct {
+	handler interface{}
+	mws     []Middleware
+}
+
+// WithMiddleware 为单个 handler 附加中间件，返回值可以传给 RegisterHandlers 或 EventBus.Subscribe，
+// 单个 handler 的中间件在 EventBus.Use 注册的全局中间件之内执行
+func WithMiddleware(handler interface{}, mws ...Middleware) interface{} {
+	return &middlewareHandler{handler: handler, mws: mws}
+}
+
+// Recovery 捕获 handler 的 panic 并打印堆栈，返回 ErrHandlerPanic，不影响后续 handler 和 websocket 连接
+func Recovery() Middleware {
+	return func(next EventHandlerFunc) EventHandlerFunc {
+		return func(event *types.WSPayload, data interface{}) (err error) {
+			defer func() {
+				if e := recover(); e != nil {
+					buf := make([]byte, 4096)
+					buf = buf[:runtime.Stack(buf, false)]
+					log.Printf("[PANIC] event type=%s id=%s\n%v\n%s\n", event.Type, event.ID, e, buf)
+					err = fmt.Errorf("%w: %v", ErrHandlerPanic, e)
+				}
+			}()
+			return next(event, data)
+		}
+	}
+}
+
+// Timing 统计 handler 的执行耗时，执行结束后调用 observe
+func Timing(observe func(event *types.WSPayload, elapsed time.Duration, err error)) Middleware {
+	return func(next EventHandlerFunc) EventHandlerFunc {
+		return func(event *types.WSPayload, data interface{}) error {
+			start := time.Now()
+			err := next(event, data)
+			observe(event, time.Since(start), err)
+			return err
+		}
+	}
+}
+
+// Logging 以 key=value 的格式记录每个事件的类型、ID、来源、耗时和错误
+func Logging() Middleware {
+	return func(next EventHandlerFunc) EventHandlerFunc {
+		return func(event *types.WSPayload, data interface{}) error {
+			start := time.Now()
+			err := next(event, data)
+			guildID, channelID := eventScope(data)
+			if err != nil && !errors.Is(err, ErrStopPropagation) {
+				log.Printf("[event] type=%s id=%s guild=%s channel=%s elapsed=%s err=%q",
+					event.Type, event.ID, guildID, channelID, time.Since(start), err)
+			} else {
+				log.Printf("[event] type=%s id=%s guild=%s channel=%s elapsed=%s",
+					event.Type, event.ID, guildID, channelID, time.Since(start))
+			}
+			return err
+		}
+	}
+}
+
+// Filter 只有 allow 返回 true 时才调用 handler，否则直接跳过
+func Filter(allow func(event *types.WSPayload, data interface{}) bool) Middleware {
+	return func(next EventHandlerFunc) EventHandlerFunc {
+		return func(event *types.WSPayload, data interface{}) error {
+			if !allow(event, data) {
+				return nil
+			}
+			return next(event, data)
+		}
+	}
+}
+
+// IgnoreBots 跳过机器人发送的消息和机器人成员的事件
+func IgnoreBots() Middleware {
+	return Filter(func(event *types.WSPayload, data interface{}) bool {
+		var user *types.User
+		switch d := data.(type) {
+		case *types.Message:
+			user = d.Author
+		case *types.Member:
+			user = d.User
+		}
+		return user == nil || !user.Bot
+	})
+}
+
+// GuildAllowlist 只处理指定频道的事件，群聊、单聊等没有频道ID的事件不受影响
+func GuildAllowlist(guildIDs ...string) Middleware {
+	allowed := stringSet(guildIDs)
+	return Filter(func(event *types.WSPayload, data interface{}) bool {
+		guildID, _ := eventScope(data)
+		return guildID == "" || allowed[guildID]
+	})
+}
+
+// ChannelScope 只处理指定子频道的事件，没有子频道ID的事件不受影响
+func ChannelScope(channelIDs ...string) Middleware {
+	allowed := stringSet(channelIDs)
+	return Filter(func(event *types.WSPayload, data interface{}) bool {
+		_, channelID := eventScope(data)
+		return channelID == "" || allowed[channelID]
+	})
+}
+
+// eventScope 返回事件数据所属的频道ID和子频道ID，没有时返回空字符串
+func eventScope(data interface{}) (guildID, channelID string) {
+	switch d := data.(type) {
+	case *types.Message:
+		return d.GuildID, d.ChannelID
+	case *types.Guild:
+		return d.ID, ""
+	case *types.Channel:
+		return d.GuildID, d.ID
+	case *types.Member:
+		return d.GuildID, ""
+	case *types.MessageReaction:
+		return d.GuildID, d.ChannelID
+	case *types.Interaction:
+		return d.GuildID, d.ChannelID
+	}
+	return "", ""
+}
+
+// stringSet 将字符串切片转换为集合
+func stringSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
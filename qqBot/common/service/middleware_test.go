@@ -0,0 +1,104 @@
+package service
+
+import (
+	"errors"
+	"qqbot/common/types"
+	"qqbot/constant"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMiddleware(t *testing.T) {
+	payload := &types.WSPayload{}
+	payload.Type = eventATMessage
+
+	t.Run("test global and per handler order", func(t *testing.T) {
+		bus := NewEventBus()
+		var calls []string
+		mark := func(name string) Middleware {
+			return func(next EventHandlerFunc) EventHandlerFunc {
+				return func(event *types.WSPayload, data interface{}) error {
+					calls = append(calls, name+" before")
+					err := next(event, data)
+					calls = append(calls, name+" after")
+					return err
+				}
+			}
+		}
+		var handler ATMessageEventHandler = func(*types.WSPayload, *types.Message) error {
+			calls = append(calls, "handler")
+			return nil
+		}
+		wrapped := WithMiddleware(handler, mark("local1"), mark("local2"))
+		if HandlerIntent(wrapped) != constant.IntentGuildAtMessages {
+			t.Fatalf("unexpected intent %d", HandlerIntent(wrapped))
+		}
+		_, _ = bus.Subscribe(wrapped, DefaultPriority)
+		// 全局中间件在订阅之后注册同样生效
+		bus.Use(mark("global"))
+		_ = bus.Publish(eventATMessage, payload, &types.Message{})
+		want := []string{"global before", "local1 before", "local2 before", "handler", "local2 after", "local1 after", "global after"}
+		if !reflect.DeepEqual(calls, want) {
+			t.Fatalf("unexpected calls %v", calls)
+		}
+	})
+
+	t.Run("test recovery keeps other handlers running", func(t *testing.T) {
+		bus := NewEventBus()
+		bus.Use(Recovery())
+		var called bool
+		var panics ATMessageEventHandler = func(*types.WSPayload, *types.Message) error { panic("boom") }
+		var after ATMessageEventHandler = func(*types.WSPayload, *types.Message) error {
+			called = true
+			return nil
+		}
+		_, _ = bus.Subscribe(panics, 1)
+		_, _ = bus.Subscribe(after, 0)
+		if err := bus.Publish(eventATMessage, payload, &types.Message{}); !errors.Is(err, ErrHandlerPanic) || !called {
+			t.Fatalf("unexpected err %v, called %v", err, called)
+		}
+	})
+
+	t.Run("test filters", func(t *testing.T) {
+		var count int
+		var handler ATMessageEventHandler = func(*types.WSPayload, *types.Message) error {
+			count++
+			return nil
+		}
+		bus := NewEventBus()
+		_, _ = bus.Subscribe(WithMiddleware(handler, IgnoreBots(), GuildAllowlist("g1"), ChannelScope("c1", "c2")), DefaultPriority)
+		cases := []struct {
+			data *types.Message
+			want bool
+		}{
+			{&types.Message{GuildID: "g1", ChannelID: "c2", Author: &types.User{ID: "u1"}}, true},
+			{&types.Message{GuildID: "g1", ChannelID: "c1", Author: &types.User{ID: "b1", Bot: true}}, false},
+			{&types.Message{GuildID: "g2", ChannelID: "c1", Author: &types.User{ID: "u1"}}, false},
+			{&types.Message{GuildID: "g1", ChannelID: "c3", Author: &types.User{ID: "u1"}}, false},
+			{&types.Message{GroupOpenID: "group1", Author: &types.User{ID: "u1"}}, true},
+		}
+		for i, c := range cases {
+			count = 0
+			_ = bus.Publish(eventATMessage, payload, c.data)
+			if (count == 1) != c.want {
+				t.Fatalf("case %d: handler called %d times", i, count)
+			}
+		}
+	})
+
+	t.Run("test timing", func(t *testing.T) {
+		errFailed := errors.New("failed")
+		var elapsed time.Duration
+		var observed error
+		fn := Timing(func(event *types.WSPayload, d time.Duration, err error) {
+			elapsed, observed = d, err
+		})(func(*types.WSPayload, interface{}) error {
+			time.Sleep(10 * time.Millisecond)
+			return errFailed
+		})
+		if err := fn(payload, nil); err != errFailed || observed != errFailed || elapsed < 10*time.Millisecond {
+			t.Fatalf("unexpected err %v, observed %v after %s", err, observed, elapsed)
+		}
+	})
+}
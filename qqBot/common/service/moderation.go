@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"qqbot/common/types"
+	"qqbot/constant"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// MemberDeleteOption 移除成员的可选参数
+type MemberDeleteOption func(opts *types.MemberDeleteOpts)
+
+// WithAddBlacklist 移除成员的同时将其加入黑名单
+func WithAddBlacklist() MemberDeleteOption {
+	return func(opts *types.MemberDeleteOpts) {
+		opts.AddBlacklist = true
+	}
+}
+
+// WithDeleteHistoryMsgDays 移除成员的同时撤回其消息，days 为 3/7/15/30 或 -1(全部)
+func WithDeleteHistoryMsgDays(days int) MemberDeleteOption {
+	return func(opts *types.MemberDeleteOpts) {
+		opts.DeleteHistoryMsgDays = days
+	}
+}
+
+// MuteGuild 全员禁言，使用 types.MuteFor 或 types.MuteUntil 构造禁言参数
+func (client *HttpClient) MuteGuild(ctx context.Context, guildID string, mute *types.UpdateGuildMute) error {
+	m := *mute
+	m.UserIDs = nil
+	_, err := client.restyClient.R().SetContext(ctx).
+		SetPathParam("guild_id", guildID).
+		SetBody(&m).
+		Patch(constant.GuildMuteURI)
+	return err
+}
+
+// MuteMember 禁言单个成员
+func (client *HttpClient) MuteMember(ctx context.Context, guildID, userID string, mute *types.UpdateGuildMute) error {
+	_, err := client.restyClient.R().SetContext(ctx).
+		SetPathParams(map[string]string{"guild_id": guildID, "user_id": userID}).
+		SetBody(mute).
+		Patch(constant.MemberMuteURI)
+	return err
+}
+
+// MuteMembers 批量禁言成员，返回设置成功的成员ID
+func (client *HttpClient) MuteMembers(ctx context.Context, guildID string, userIDs []string, mute *types.UpdateGuildMute) ([]string, error) {
+	m := *mute
+	m.UserIDs = userIDs
+	resp, err := client.restyClient.R().SetContext(ctx).
+		SetResult(types.MuteMembersResult{}).
+		SetPathParam("guild_id", guildID).
+		SetBody(&m).
+		Patch(constant.GuildMuteURI)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Result().(*types.MuteMembersResult).UserIDs, nil
+}
+
+// DeleteGuildMember 将成员移出频道
+func (client *HttpClient) DeleteGuildMember(ctx context.Context, guildID, userID string, opts ...MemberDeleteOption) error {
+	body := &types.MemberDeleteOpts{}
+	for _, opt := range opts {
+		opt(body)
+	}
+	_, err := client.restyClient.R().SetContext(ctx).
+		SetPathParams(map[string]string{"guild_id": guildID, "user_id": userID}).
+		SetBody(body).
+		Delete(constant.GuildMemberURI)
+	return err
+}
+
+// AddMemberToRole 将成员添加到身份组，channelID 仅在身份组为子频道管理员时需要指定
+func (client *HttpClient) AddMemberToRole(ctx context.Context, guildID, userID, roleID, channelID string) error {
+	_, err := client.memberRoleRequest(ctx, guildID, userID, roleID, channelID).Put(constant.MemberRoleURI)
+	return err
+}
+
+// RemoveMemberFromRole 将成员移出身份组，channelID 仅在身份组为子频道管理员时需要指定
+func (client *HttpClient) RemoveMemberFromRole(ctx context.Context, guildID, userID, roleID, channelID string) error {
+	_, err := client.memberRoleRequest(ctx, guildID, userID, roleID, channelID).Delete(constant.MemberRoleURI)
+	return err
+}
+
+func (client *HttpClient) memberRoleRequest(ctx context.Context, guildID, userID, roleID, channelID string) *resty.Request {
+	r := client.restyClient.R().SetContext(ctx).
+		SetPathParams(map[string]string{"guild_id": guildID, "user_id": userID, "role_id": roleID})
+	if channelID != "" {
+		body := &types.RoleChannel{}
+		body.Channel.ID = channelID
+		r.SetBody(body)
+	}
+	return r
+}
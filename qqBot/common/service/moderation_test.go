@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"qqbot/common/service/servicetest"
+	"qqbot/common/types"
+	"testing"
+	"time"
+)
+
+func TestModeration(t *testing.T) {
+	ctx := context.Background()
+	server := servicetest.NewOpenAPIServer()
+	defer server.Close()
+	server.Reply(http.MethodPatch, "/guilds/g1/members/u1/mute", http.StatusNoContent, nil)
+	server.Handle(http.MethodPatch, "/guilds/g1/mute", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"user_ids":["u1"]}`))
+	})
+	server.Reply(http.MethodDelete, "/guilds/g1/members/u1", http.StatusNoContent, nil)
+	server.Reply(http.MethodPut, "/guilds/g1/members/u1/roles/5", http.StatusNoContent, nil)
+	server.Reply(http.MethodDelete, "/guilds/g1/members/u1/roles/2", http.StatusNoContent, nil)
+	client := NewClient(1024, "token", time.Second, WithBaseURL(server.URL))
+	lastBody := func() string { return string(server.LastRequest().Body) }
+
+	t.Run("test mute", func(t *testing.T) {
+		if err := client.MuteMember(ctx, "g1", "u1", types.MuteFor(10*time.Minute)); err != nil || lastBody() != `{"mute_seconds":"600"}` {
+			t.Fatalf("mute member failed: %v, body %s", err, lastBody())
+		}
+		until := time.Unix(1725442341, 0)
+		if err := client.MuteGuild(ctx, "g1", types.MuteUntil(until)); err != nil || lastBody() != `{"mute_end_timestamp":"1725442341"}` {
+			t.Fatalf("mute guild failed: %v, body %s", err, lastBody())
+		}
+		userIDs, err := client.MuteMembers(ctx, "g1", []string{"u1", "u2"}, types.MuteFor(time.Minute))
+		if err != nil || len(userIDs) != 1 || lastBody() != `{"mute_seconds":"60","user_ids":["u1","u2"]}` {
+			t.Fatalf("mute members failed: %v %v, body %s", userIDs, err, lastBody())
+		}
+	})
+
+	t.Run("test kick", func(t *testing.T) {
+		if err := client.DeleteGuildMember(ctx, "g1", "u1", WithAddBlacklist(), WithDeleteHistoryMsgDays(-1)); err != nil {
+			t.Fatalf("delete member failed: %v", err)
+		}
+		opts := &types.MemberDeleteOpts{}
+		_ = json.Unmarshal(server.LastRequest().Body, opts)
+		if !opts.AddBlacklist || opts.DeleteHistoryMsgDays != -1 {
+			t.Fatalf("unexpected body %s", lastBody())
+		}
+	})
+
+	t.Run("test roles", func(t *testing.T) {
+		if err := client.AddMemberToRole(ctx, "g1", "u1", types.RoleIDChannelAdmin, "c1"); err != nil || lastBody() != `{"channel":{"id":"c1"}}` {
+			t.Fatalf("add role failed: %v, body %s", err, lastBody())
+		}
+		if err := client.RemoveMemberFromRole(ctx, "g1", "u1", types.RoleIDAdmin, ""); err != nil || lastBody() != "" {
+			t.Fatalf("remove role failed: %v, body %s", err, lastBody())
+		}
+	})
+}
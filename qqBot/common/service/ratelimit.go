@@ -0,0 +1,238 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	defaultRateLimit     = 5 // 每个 route + 子频道每秒允许的请求数
+	defaultRateBurst     = 5
+	defaultRetryCount    = 2
+	defaultRetryWait     = 200 * time.Millisecond
+	defaultRetryMaxWait  = 3 * time.Second
+	defaultRateLimitWait = time.Second // 429 响应没有指明等待时间时的默认等待时间
+	maxRateBuckets       = 4096        // 超过后清理空闲的限频桶
+)
+
+// rateLimitScopeParams 限频的作用范围，按顺序取请求中第一个存在的路径参数
+var rateLimitScopeParams = []string{"channel_id", "guild_id", "group_openid", "openid"}
+
+// rateLimitKeyCtx 在请求 context 中保存限频 key，响应时据此记录服务端要求的等待时间
+type rateLimitKeyCtx struct{}
+
+// WithRateLimit 设置每个 route + 子频道每秒允许的请求数和突发数，rate <= 0 表示不限制
+func WithRateLimit(rate float64, burst int) ClientOption {
+	return func(client *HttpClient) {
+		client.limiter = newRouteLimiter(rate, burst)
+	}
+}
+
+// WithRetry 设置幂等请求在 5xx、网络错误时以及所有请求在 429 时的重试次数和退避时间，count 为 0 表示不重试
+func WithRetry(count int, wait time.Duration, maxWait time.Duration) ClientOption {
+	return func(client *HttpClient) {
+		client.retryCount = count
+		client.retryWait = wait
+		client.retryMaxWait = maxWait
+	}
+}
+
+// bucket 令牌桶，tokens 可以为负数，表示已经被预定的令牌
+type bucket struct {
+	tokens       float64
+	last         time.Time
+	blockedUntil time.Time // 服务端要求等待到的时间
+}
+
+// routeLimiter 按 route + 子频道区分的客户端限频器
+type routeLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	buckets map[string]*bucket
+}
+
+func newRouteLimiter(rate float64, burst int) *routeLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &routeLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// wait 等待直到 key 可以发起请求
+func (l *routeLimiter) wait(ctx context.Context, key string) error {
+	for {
+		delay, reserved := l.reserve(key)
+		if delay <= 0 {
+			return nil
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		// 已经预定了令牌，等待结束即可发起请求；被服务端限频时需要重新预定
+		if reserved {
+			return nil
+		}
+	}
+}
+
+// reserve 预定一个令牌，返回需要等待的时间以及是否已经预定成功
+func (l *routeLimiter) reserve(key string) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	b := l.bucketLocked(key, now)
+	if now.Before(b.blockedUntil) {
+		return b.blockedUntil.Sub(now), false
+	}
+	if l.rate <= 0 {
+		return 0, true
+	}
+	// 按时间补充令牌
+	b.tokens += now.Sub(b.last).Seconds() * l.rate
+	if b.tokens > l.burst {
+		b.tokens = l.burst
+	}
+	b.last = now
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0, true
+	}
+	return time.Duration(-b.tokens / l.rate * float64(time.Second)), true
+}
+
+// block 服务端要求 key 等待到 until 之后才能继续请求
+func (l *routeLimiter) block(key string, until time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b := l.bucketLocked(key, time.Now())
+	if until.After(b.blockedUntil) {
+		b.blockedUntil = until
+	}
+}
+
+// bucketLocked 获取 key 对应的令牌桶，调用方需持有 l.mu
+func (l *routeLimiter) bucketLocked(key string, now time.Time) *bucket {
+	b, ok := l.buckets[key]
+	if ok {
+		return b
+	}
+	if len(l.buckets) >= maxRateBuckets {
+		l.pruneLocked(now)
+	}
+	b = &bucket{tokens: l.burst, last: now}
+	l.buckets[key] = b
+	return b
+}
+
+// pruneLocked 清理已经补满且没有被服务端限频的令牌桶，调用方需持有 l.mu
+func (l *routeLimiter) pruneLocked(now time.Time) {
+	for key, b := range l.buckets {
+		full := l.rate <= 0 || b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst
+		if full && now.After(b.blockedUntil) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// rateLimitKey 根据请求的 route 模板和作用范围生成限频 key，需要在路径参数替换之前调用
+func rateLimitKey(r *resty.Request) string {
+	key := r.Method + " " + r.URL
+	for _, param := range rateLimitScopeParams {
+		if value, ok := r.PathParams[param]; ok {
+			return key + " " + value
+		}
+	}
+	return key
+}
+
+// waitRateLimit 请求前等待限频，并在 context 中记录限频 key
+func (client *HttpClient) waitRateLimit(_ *resty.Client, r *resty.Request) error {
+	if client.limiter == nil {
+		return nil
+	}
+	key := rateLimitKey(r)
+	r.SetContext(context.WithValue(r.Context(), rateLimitKeyCtx{}, key))
+	return client.limiter.wait(r.Context(), key)
+}
+
+// observeRateLimit 根据 429 响应和限频响应头，记录服务端要求的等待时间
+func (client *HttpClient) observeRateLimit(_ *resty.Client, resp *resty.Response) error {
+	if client.limiter == nil {
+		return nil
+	}
+	key, ok := resp.Request.Context().Value(rateLimitKeyCtx{}).(string)
+	if !ok {
+		return nil
+	}
+	if wait := rateLimitWait(resp); wait > 0 {
+		client.limiter.block(key, time.Now().Add(wait))
+	}
+	return nil
+}
+
+// rateLimitWait 从响应中解析需要等待的时间，支持 Retry-After 以及 X-RateLimit-Remaining/X-RateLimit-Reset
+func rateLimitWait(resp *resty.Response) time.Duration {
+	header := resp.Header()
+	if seconds, err := strconv.Atoi(header.Get("Retry-After")); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			if wait := time.Until(time.Unix(reset, 0)); wait > 0 {
+				return wait
+			}
+		}
+	}
+	if resp.StatusCode() == http.StatusTooManyRequests {
+		return defaultRateLimitWait
+	}
+	return 0
+}
+
+// retryAfter 重试等待时间，优先使用服务端要求的等待时间，返回 0 时使用带抖动的指数退避
+func retryAfter(_ *resty.Client, resp *resty.Response) (time.Duration, error) {
+	return rateLimitWait(resp), nil
+}
+
+// shouldRetry 429 可以安全重试；5xx 和网络错误只重试幂等请求，避免重复发送消息
+func shouldRetry(resp *resty.Response, err error) bool {
+	if err == nil {
+		return false
+	}
+	if apiErr, ok := AsOpenAPIError(err); ok {
+		if apiErr.HTTPStatus == http.StatusTooManyRequests {
+			return true
+		}
+		return apiErr.HTTPStatus >= http.StatusInternalServerError && isIdempotent(resp)
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && isIdempotent(resp)
+}
+
+// isIdempotent 判断请求是否幂等
+func isIdempotent(resp *resty.Response) bool {
+	if resp == nil || resp.Request == nil {
+		return false
+	}
+	switch resp.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
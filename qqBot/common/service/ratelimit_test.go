@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"qqbot/common/service/servicetest"
+	"qqbot/common/types"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("test limiter keyed by route and channel", func(t *testing.T) {
+		limiter := newRouteLimiter(10, 1)
+		start := time.Now()
+		_ = limiter.wait(ctx, "POST /channels/{channel_id}/messages 1")
+		// 其他子频道不受影响
+		_ = limiter.wait(ctx, "POST /channels/{channel_id}/messages 2")
+		if time.Since(start) > 50*time.Millisecond {
+			t.Fatalf("different channels should not wait for each other")
+		}
+		_ = limiter.wait(ctx, "POST /channels/{channel_id}/messages 1")
+		if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+			t.Fatalf("second request in the same channel should wait, elapsed %v", elapsed)
+		}
+	})
+
+	t.Run("test limiter blocked by server", func(t *testing.T) {
+		limiter := newRouteLimiter(0, 1)
+		limiter.block("k", time.Now().Add(50*time.Millisecond))
+		start := time.Now()
+		_ = limiter.wait(ctx, "k")
+		if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+			t.Fatalf("blocked key should wait, elapsed %v", elapsed)
+		}
+		cancelCtx, cancel := context.WithCancel(ctx)
+		cancel()
+		limiter.block("k", time.Now().Add(time.Minute))
+		if err := limiter.wait(cancelCtx, "k"); err == nil {
+			t.Fatalf("wait should stop when context is canceled")
+		}
+	})
+
+	t.Run("test idempotent request retried on 5xx", func(t *testing.T) {
+		server := servicetest.NewOpenAPIServer()
+		defer server.Close()
+		var calls atomic.Int32
+		server.Handle(http.MethodGet, "/gateway/bot", func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) < 3 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"url":"wss://fake"}`))
+		})
+		client := NewClient(1024, "token", time.Second, WithBaseURL(server.URL),
+			WithRetry(3, time.Millisecond, 10*time.Millisecond))
+		ap, err := client.GetWSS(ctx)
+		if err != nil || ap.URL != "wss://fake" || calls.Load() != 3 {
+			t.Fatalf("unexpected gateway %+v, err %v, calls %d", ap, err, calls.Load())
+		}
+	})
+
+	t.Run("test post not retried on 5xx but retried on 429", func(t *testing.T) {
+		server := servicetest.NewOpenAPIServer()
+		defer server.Close()
+		server.Reply(http.MethodPost, "/channels/1/messages", http.StatusInternalServerError, map[string]interface{}{})
+		var calls atomic.Int32
+		server.Handle(http.MethodPost, "/channels/2/messages", func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) == 1 {
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"id":"m1"}`))
+		})
+		client := NewClient(1024, "token", time.Second, WithBaseURL(server.URL),
+			WithRetry(3, time.Millisecond, 10*time.Millisecond))
+
+		if _, err := client.PostMessage(ctx, "1", &types.MessageToCreate{}); !IsServerError(err) {
+			t.Fatalf("expect server error, got %v", err)
+		}
+		if len(server.Requests()) != 1 {
+			t.Fatalf("post should not be retried on 5xx, requested %d times", len(server.Requests()))
+		}
+		msg, err := client.PostMessage(ctx, "2", &types.MessageToCreate{})
+		if err != nil || msg.ID != "m1" || calls.Load() != 2 {
+			t.Fatalf("post should be retried on 429, got %+v, err %v, calls %d", msg, err, calls.Load())
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"qqbot/common/types"
+	"qqbot/constant"
+	"strconv"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// CreateMessageReaction 机器人对消息发表表情表态
+func (client *HttpClient) CreateMessageReaction(ctx context.Context, channelID, messageID string, emoji types.Emoji) error {
+	_, err := client.reactionRequest(ctx, channelID, messageID, emoji).Put(constant.MessageReactionURI)
+	return err
+}
+
+// DeleteOwnMessageReaction 删除机器人发表的表情表态
+func (client *HttpClient) DeleteOwnMessageReaction(ctx context.Context, channelID, messageID string, emoji types.Emoji) error {
+	_, err := client.reactionRequest(ctx, channelID, messageID, emoji).Delete(constant.MessageReactionURI)
+	return err
+}
+
+// GetMessageReactionUsers 拉取对消息发表了某个表情表态的用户列表，pager 为 nil 时拉取第一页
+func (client *HttpClient) GetMessageReactionUsers(ctx context.Context, channelID, messageID string, emoji types.Emoji,
+	pager *types.ReactionUsersPager) (*types.MessageReactionUsers, error) {
+	r := client.reactionRequest(ctx, channelID, messageID, emoji).SetResult(types.MessageReactionUsers{})
+	if pager != nil {
+		if pager.Cookie != "" {
+			r.SetQueryParam("cookie", pager.Cookie)
+		}
+		if pager.Limit > 0 {
+			r.SetQueryParam("limit", strconv.Itoa(pager.Limit))
+		}
+	}
+	resp, err := r.Get(constant.MessageReactionURI)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Result().(*types.MessageReactionUsers), nil
+}
+
+// reactionRequest 设置表情表态接口的路径参数
+func (client *HttpClient) reactionRequest(ctx context.Context, channelID, messageID string, emoji types.Emoji) *resty.Request {
+	return client.restyClient.R().SetContext(ctx).
+		SetPathParams(map[string]string{
+			"channel_id": channelID,
+			"message_id": messageID,
+			"emoji_type": strconv.Itoa(int(emoji.Type)),
+			"emoji_id":   emoji.ID,
+		})
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"qqbot/common/service/servicetest"
+	"qqbot/common/types"
+	"testing"
+	"time"
+)
+
+func TestMessageReaction(t *testing.T) {
+	ctx := context.Background()
+	emoji := types.Emoji{ID: "4", Type: types.EmojiTypeSystem}
+	const path = "/channels/c1/messages/m1/reactions/1/4"
+
+	t.Run("test add, remove and list reactions", func(t *testing.T) {
+		server := servicetest.NewOpenAPIServer()
+		defer server.Close()
+		server.Reply(http.MethodPut, path, http.StatusNoContent, nil)
+		server.Reply(http.MethodDelete, path, http.StatusNoContent, nil)
+		server.Reply(http.MethodGet, path, http.StatusOK, &types.MessageReactionUsers{
+			Users: []*types.User{{ID: "u1"}}, Cookie: "next", IsEnd: false,
+		})
+		client := NewClient(1024, "token", time.Second, WithBaseURL(server.URL))
+
+		if err := client.CreateMessageReaction(ctx, "c1", "m1", emoji); err != nil {
+			t.Fatalf("add reaction failed: %v", err)
+		}
+		if err := client.DeleteOwnMessageReaction(ctx, "c1", "m1", emoji); err != nil {
+			t.Fatalf("remove reaction failed: %v", err)
+		}
+		users, err := client.GetMessageReactionUsers(ctx, "c1", "m1", emoji, &types.ReactionUsersPager{Cookie: "prev", Limit: 50})
+		if err != nil || len(users.Users) != 1 || users.Cookie != "next" {
+			t.Fatalf("unexpected users %+v, err %v", users, err)
+		}
+		if query := server.LastRequest().Query; query.Get("cookie") != "prev" || query.Get("limit") != "50" {
+			t.Fatalf("unexpected query %v", query)
+		}
+	})
+
+	t.Run("test reaction event parsed", func(t *testing.T) {
+		received := make(chan *types.MessageReaction, 1)
+		var handler MessageReactionEventHandler = func(event *types.WSPayload, data *types.MessageReaction) error {
+			received <- data
+			return nil
+		}
+		sub, _ := DefaultEventBus.Subscribe(handler, DefaultPriority)
+		defer sub.Unsubscribe()
+
+		dispatch(t, EventMessageReactionAdd, `{"user_id":"u1","guild_id":"g1","channel_id":"c1",`+
+			`"target":{"id":"m1","type":0},"emoji":{"id":"4","type":1}}`)
+		data := <-received
+		if data.Target.ID != "m1" || data.Target.Type != types.ReactionTargetTypeMessage || data.Emoji != emoji {
+			t.Fatalf("unexpected reaction %+v", data)
+		}
+	})
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"log"
 	"qqbot/common/types"
 	constant "qqbot/constant"
 	"qqbot/utils"
@@ -9,23 +10,128 @@ import (
 // ATMessageEventHandler 处理 AT 消息事件的回调函数
 type ATMessageEventHandler func(event *types.WSPayload, data *types.Message) error
 
+// DirectMessageEventHandler 处理私信消息事件的回调函数
+type DirectMessageEventHandler func(event *types.WSPayload, data *types.Message) error
+
+// GroupATMessageEventHandler 处理群聊 @机器人 消息事件的回调函数
+type GroupATMessageEventHandler func(event *types.WSPayload, data *types.Message) error
+
+// C2CMessageEventHandler 处理单聊消息事件的回调函数
+type C2CMessageEventHandler func(event *types.WSPayload, data *types.Message) error
+
+// GuildEventHandler 处理频道事件的回调函数，通过 event.Type 区分 GUILD_CREATE、GUILD_UPDATE、GUILD_DELETE
+type GuildEventHandler func(event *types.WSPayload, data *types.Guild) error
+
+// ChannelEventHandler 处理子频道事件的回调函数，通过 event.Type 区分 CHANNEL_CREATE、CHANNEL_UPDATE、CHANNEL_DELETE
+type ChannelEventHandler func(event *types.WSPayload, data *types.Channel) error
+
+// GuildMemberEventHandler 处理频道成员事件的回调函数，通过 event.Type 区分 GUILD_MEMBER_ADD、GUILD_MEMBER_UPDATE、GUILD_MEMBER_REMOVE
+type GuildMemberEventHandler func(event *types.WSPayload, data *types.Member) error
+
+// MessageReactionEventHandler 处理表情表态事件的回调函数，通过 event.Type 区分 MESSAGE_REACTION_ADD、MESSAGE_REACTION_REMOVE
+type MessageReactionEventHandler func(event *types.WSPayload, data *types.MessageReaction) error
+
+// InteractionEventHandler 处理互动事件的回调函数，收到后需要调用 PutInteraction 回应
+type InteractionEventHandler func(event *types.WSPayload, data *types.Interaction) error
+
 // eventParseFunc 解析 WebSocket 事件的回调函数
 type eventParseFunc func(event *types.WSPayload, message []byte) error
 
-// DefaultHandlers 管理所有支持的事件处理器
-var DefaultHandlers struct {
-	ATMessage ATMessageEventHandler
+// 事件种类，同一种类的事件由同一类型的 handler 处理
+const (
+	eventATMessage      = "AT_MESSAGE_CREATE"
+	eventDirectMessage  = "DIRECT_MESSAGE_CREATE"
+	eventGroupATMessage = "GROUP_AT_MESSAGE_CREATE"
+	eventC2CMessage     = "C2C_MESSAGE_CREATE"
+	eventGuild          = "GUILD"
+	eventChannel        = "CHANNEL"
+	eventGuildMember    = "GUILD_MEMBER"
+	eventReaction       = "MESSAGE_REACTION"
+	eventInteraction    = "INTERACTION_CREATE"
+)
+
+// 频道和子频道事件类型
+const (
+	EventGuildCreate   = "GUILD_CREATE"
+	EventGuildUpdate   = "GUILD_UPDATE"
+	EventGuildDelete   = "GUILD_DELETE"
+	EventChannelCreate = "CHANNEL_CREATE"
+	EventChannelUpdate = "CHANNEL_UPDATE"
+	EventChannelDelete = "CHANNEL_DELETE"
+)
+
+// 频道成员事件类型
+const (
+	EventGuildMemberAdd    = "GUILD_MEMBER_ADD"
+	EventGuildMemberUpdate = "GUILD_MEMBER_UPDATE"
+	EventGuildMemberRemove = "GUILD_MEMBER_REMOVE"
+)
+
+// 表情表态事件类型
+const (
+	EventMessageReactionAdd    = "MESSAGE_REACTION_ADD"
+	EventMessageReactionRemove = "MESSAGE_REACTION_REMOVE"
+)
+
+// handlerInfo 根据 handler 的类型返回对应的事件种类、intent 以及统一签名的处理函数
+func handlerInfo(handler interface{}) (kind string, intent int, fn EventHandlerFunc, ok bool) {
+	switch handle := handler.(type) {
+	case *middlewareHandler:
+		kind, intent, fn, ok = handlerInfo(handle.handler)
+		if ok {
+			fn = Chain(handle.mws...)(fn)
+		}
+		return kind, intent, fn, ok
+	case ATMessageEventHandler:
+		return eventATMessage, constant.IntentGuildAtMessages, func(event *types.WSPayload, data interface{}) error {
+			return handle(event, data.(*types.Message))
+		}, true
+	case DirectMessageEventHandler:
+		return eventDirectMessage, constant.IntentDirectMessages, func(event *types.WSPayload, data interface{}) error {
+			return handle(event, data.(*types.Message))
+		}, true
+	case GroupATMessageEventHandler:
+		return eventGroupATMessage, constant.IntentGroupAndC2C, func(event *types.WSPayload, data interface{}) error {
+			return handle(event, data.(*types.Message))
+		}, true
+	case C2CMessageEventHandler:
+		return eventC2CMessage, constant.IntentGroupAndC2C, func(event *types.WSPayload, data interface{}) error {
+			return handle(event, data.(*types.Message))
+		}, true
+	case GuildEventHandler:
+		return eventGuild, constant.IntentGuilds, func(event *types.WSPayload, data interface{}) error {
+			return handle(event, data.(*types.Guild))
+		}, true
+	case ChannelEventHandler:
+		return eventChannel, constant.IntentGuilds, func(event *types.WSPayload, data interface{}) error {
+			return handle(event, data.(*types.Channel))
+		}, true
+	case GuildMemberEventHandler:
+		return eventGuildMember, constant.IntentGuildMembers, func(event *types.WSPayload, data interface{}) error {
+			return handle(event, data.(*types.Member))
+		}, true
+	case MessageReactionEventHandler:
+		return eventReaction, constant.IntentGuildMessageReactions, func(event *types.WSPayload, data interface{}) error {
+			return handle(event, data.(*types.MessageReaction))
+		}, true
+	case InteractionEventHandler:
+		return eventInteraction, constant.IntentInteraction, func(event *types.WSPayload, data interface{}) error {
+			return handle(event, data.(*types.Interaction))
+		}, true
+	}
+	return "", 0, nil, false
 }
 
 // RegisterHandlers 注册事件回调,并返回用于 WebSocket 鉴权的 intent
+// 每次调用都会新增订阅，不会覆盖之前注册的 handler
 func RegisterHandlers(handlers ...interface{}) int {
 	var intent int
 	for _, h := range handlers {
-		switch handle := h.(type) {
-		case ATMessageEventHandler:
-			DefaultHandlers.ATMessage = handle
-			intent |= 1 << 30 //使用位运算 |= 修改 intent 变量,设置相应的位为 1 来表示已注册该事件类型
+		if _, err := DefaultEventBus.Subscribe(h, DefaultPriority); err != nil {
+			log.Printf("[event] register handler failed, %v", err)
+			continue
 		}
+		intent |= HandlerIntent(h) //使用位运算 |= 修改 intent 变量,设置相应的位为 1 来表示已注册该事件类型
 	}
 	return intent
 }
@@ -42,21 +148,94 @@ func ParseAndHandle(payload *types.WSPayload) error {
 // eventParseFunc 解析 WebSocket 事件的回调函数
 var eventParseFuncMap = map[int]map[string]eventParseFunc{
 	constant.WSDispatchEvent: {
-		"AT_MESSAGE_CREATE": atMessageHandler,
+		eventATMessage:      messageHandler(eventATMessage),
+		eventDirectMessage:  messageHandler(eventDirectMessage),
+		eventGroupATMessage: messageHandler(eventGroupATMessage),
+		eventC2CMessage:     messageHandler(eventC2CMessage),
+		EventGuildCreate:    guildHandler,
+		EventGuildUpdate:    guildHandler,
+		EventGuildDelete:    guildHandler,
+		EventChannelCreate:  channelHandler,
+		EventChannelUpdate:  channelHandler,
+		EventChannelDelete:  channelHandler,
+
+		EventGuildMemberAdd:    guildMemberHandler,
+		EventGuildMemberUpdate: guildMemberHandler,
+		EventGuildMemberRemove: guildMemberHandler,
+
+		EventMessageReactionAdd:    reactionHandler,
+		EventMessageReactionRemove: reactionHandler,
+
+		eventInteraction: interactionHandler,
 	},
 }
 
-// atMessageHandler 解析 AT 消息事件的数据,并调用注册的 AT 消息事件处理器
-func atMessageHandler(payload *types.WSPayload, message []byte) error {
-	// 1. 解析 AT 消息事件的数据
-	data := &types.Message{}
+// messageHandler 返回解析消息事件并投递给 kind 对应处理器的解析函数，
+// 子频道和私信消息在所有处理器执行完后撤回被 MarkForDeletion 标记的消息
+func messageHandler(kind string) eventParseFunc {
+	return func(payload *types.WSPayload, message []byte) error {
+		data := &types.Message{}
+		if err := utils.ParseData(message, data); err != nil {
+			return err
+		}
+		if kind == eventATMessage || kind == eventDirectMessage {
+			return publishDeletable(kind, payload, data)
+		}
+		return DefaultEventBus.Publish(kind, payload, data)
+	}
+}
+
+// guildHandler 解析频道事件的数据，更新本地缓存后投递给所有订阅了频道事件的处理器
+func guildHandler(payload *types.WSPayload, message []byte) error {
+	data := &types.Guild{}
+	if err := utils.ParseData(message, data); err != nil {
+		return err
+	}
+	if payload.Type == EventGuildDelete {
+		DefaultState.removeGuild(data.ID)
+	} else {
+		DefaultState.setGuild(data)
+	}
+	return DefaultEventBus.Publish(eventGuild, payload, data)
+}
+
+// channelHandler 解析子频道事件的数据，更新本地缓存后投递给所有订阅了子频道事件的处理器
+func channelHandler(payload *types.WSPayload, message []byte) error {
+	data := &types.Channel{}
 	if err := utils.ParseData(message, data); err != nil {
 		return err
 	}
-	// 2. 检查是否已注册 AT 消息事件的处理器
-	if DefaultHandlers.ATMessage == nil {
-		return nil // 如果没有注册处理器,则什么也不做
+	if payload.Type == EventChannelDelete {
+		DefaultState.removeChannel(data.ID)
+	} else {
+		DefaultState.setChannel(data)
+	}
+	return DefaultEventBus.Publish(eventChannel, payload, data)
+}
+
+// guildMemberHandler 解析频道成员事件的数据，并投递给所有订阅了频道成员事件的处理器
+func guildMemberHandler(payload *types.WSPayload, message []byte) error {
+	data := &types.Member{}
+	if err := utils.ParseData(message, data); err != nil {
+		return err
+	}
+	return DefaultEventBus.Publish(eventGuildMember, payload, data)
+}
+
+// reactionHandler 解析表情表态事件的数据，并投递给所有订阅了表情表态事件的处理器
+func reactionHandler(payload *types.WSPayload, message []byte) error {
+	data := &types.MessageReaction{}
+	if err := utils.ParseData(message, data); err != nil {
+		return err
+	}
+	return DefaultEventBus.Publish(eventReaction, payload, data)
+}
+
+// interactionHandler 解析互动事件的数据，并投递给所有订阅了互动事件的处理器
+func interactionHandler(payload *types.WSPayload, message []byte) error {
+	data := &types.Interaction{}
+	if err := utils.ParseData(message, data); err != nil {
+		return err
 	}
-	// 3. 调用注册的 AT 消息事件处理器
-	return DefaultHandlers.ATMessage(payload, data)
+	return DefaultEventBus.Publish(eventInteraction, payload, data)
 }
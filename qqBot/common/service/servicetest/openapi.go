@@ -0,0 +1,88 @@
+package servicetest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+)
+
+// Request 模拟服务收到的请求
+type Request struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   []byte
+}
+
+// OpenAPIServer 模拟 OpenAPI 的测试服务，按 "METHOD /path" 注册响应并记录收到的请求，
+// 将 HttpClient 的 OpenAPI 地址指向 URL 即可在集成测试中使用
+type OpenAPIServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	routes   map[string]http.HandlerFunc
+	requests []*Request
+}
+
+// NewOpenAPIServer 启动模拟的 OpenAPI 服务，使用完毕后需要调用 Close
+func NewOpenAPIServer() *OpenAPIServer {
+	s := &OpenAPIServer{routes: make(map[string]http.HandlerFunc)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Handle 注册 method + path 的处理函数，path 为替换参数后的实际路径
+func (s *OpenAPIServer) Handle(method string, path string, handler http.HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.routes[method+" "+path] = handler
+}
+
+// Reply 注册 method + path 的固定 json 响应
+func (s *OpenAPIServer) Reply(method string, path string, status int, body interface{}) {
+	s.Handle(method, path, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(body)
+	})
+}
+
+// Requests 返回收到的所有请求
+func (s *OpenAPIServer) Requests() []*Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*Request(nil), s.requests...)
+}
+
+// LastRequest 返回最后一个收到的请求，没有请求时返回 nil
+func (s *OpenAPIServer) LastRequest() *Request {
+	requests := s.Requests()
+	if len(requests) == 0 {
+		return nil
+	}
+	return requests[len(requests)-1]
+}
+
+func (s *OpenAPIServer) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	s.mu.Lock()
+	s.requests = append(s.requests, &Request{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Query:  r.URL.Query(),
+		Header: r.Header.Clone(),
+		Body:   body,
+	})
+	handler, ok := s.routes[r.Method+" "+r.URL.Path]
+	s.mu.Unlock()
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"code":404,"message":"not found"}`))
+		return
+	}
+	handler(w, r)
+}
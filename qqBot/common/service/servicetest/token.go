@@ -0,0 +1,74 @@
+// Package servicetest 提供 service 包在测试中使用的模拟服务
+package servicetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+)
+
+// TokenServer 模拟 getAppAccessToken 接口，每次请求都会签发一个新的 access token
+type TokenServer struct {
+	*httptest.Server
+	AppID        uint64
+	ClientSecret string
+	ExpiresIn    int // 签发的 token 有效期，单位秒
+
+	requests atomic.Int32
+	failing  atomic.Bool
+}
+
+// NewTokenServer 启动模拟的 token 接口，使用完毕后需要调用 Close
+func NewTokenServer(appID uint64, clientSecret string, expiresIn int) *TokenServer {
+	s := &TokenServer{
+		AppID:        appID,
+		ClientSecret: clientSecret,
+		ExpiresIn:    expiresIn,
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveToken))
+	return s
+}
+
+// Requests 返回接口被请求的次数
+func (s *TokenServer) Requests() int {
+	return int(s.requests.Load())
+}
+
+// SetFailing 设置接口是否返回服务端错误，用于模拟 token 接口故障
+func (s *TokenServer) SetFailing(failing bool) {
+	s.failing.Store(failing)
+}
+
+// TokenFor 返回第 n 次请求签发的 token
+func (s *TokenServer) TokenFor(n int) string {
+	return fmt.Sprintf("access-token-%d", n)
+}
+
+func (s *TokenServer) serveToken(w http.ResponseWriter, r *http.Request) {
+	n := s.requests.Add(1)
+	req := struct {
+		AppID        string `json:"appId"`
+		ClientSecret string `json:"clientSecret"`
+	}{}
+	w.Header().Set("Content-Type", "application/json")
+	if s.failing.Load() {
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": 500, "message": "internal error"})
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil ||
+		req.AppID != strconv.FormatUint(s.AppID, 10) || req.ClientSecret != s.ClientSecret {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"code":    100016,
+			"message": "invalid appid or secret",
+		})
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"access_token": s.TokenFor(int(n)),
+		"expires_in":   strconv.Itoa(s.ExpiresIn),
+	})
+}
@@ -2,12 +2,12 @@ package service
 
 import (
 	"context"
-	"fmt"
 	"log"
 	"qqbot/common/types"
 	"qqbot/constant"
 	"qqbot/utils"
 	"runtime"
+	"sync"
 	"time"
 )
 
@@ -20,7 +20,7 @@ func NewSessionManager() *ChanManager {
 func (client *HttpClient) GetWSS(ctx context.Context) (*types.WebsocketAP, error) {
 	resp, err := client.restyClient.R().SetContext(ctx).
 		SetResult(types.WebsocketAP{}).
-		Get(constant.GatewayURL)
+		Get(constant.GatewayURI)
 	if err != nil {
 		return nil, err
 	}
@@ -33,8 +33,8 @@ func (client *HttpClient) PostMessage(ctx context.Context, channelID string, msg
 	resp, err := client.restyClient.R().SetContext(ctx).
 		SetResult(types.Message{}).
 		SetPathParam("channel_id", channelID).
-		SetBody(msg).
-		Post(constant.ChannelsURL)
+		SetBody(v1Message(msg)).
+		Post(constant.MessagesURI)
 	if err != nil {
 		return nil, err
 	}
@@ -42,18 +42,6 @@ func (client *HttpClient) PostMessage(ctx context.Context, channelID string, msg
 	return resp.Result().(*types.Message), nil
 }
 
-// Token 用于调用接口的 token 结构
-type Token struct {
-	AppID       uint64
-	AccessToken string
-	Type        string
-}
-
-// ToStr 将 Token 转换为字符串形式
-func (tk *Token) ToStr() string {
-	return fmt.Sprintf("%v.%s", tk.AppID, tk.AccessToken)
-}
-
 // New 创建本地 session manager 实例
 func New() *ChanManager {
 	return &ChanManager{}
@@ -62,10 +50,21 @@ func New() *ChanManager {
 // ChanManager 默认的本地 session manager 实现
 type ChanManager struct {
 	sessionChan chan Session
+	clients     sync.Map // 每个 shard 当前的连接，key 为 ShardID，value 为 *WebsocketClient
+}
+
+// Latencies 返回每个 shard 当前连接的网关时延，用于监控
+func (l *ChanManager) Latencies() map[uint32]time.Duration {
+	latencies := make(map[uint32]time.Duration)
+	l.clients.Range(func(key, value interface{}) bool {
+		latencies[key.(uint32)] = value.(*WebsocketClient).Latency()
+		return true
+	})
+	return latencies
 }
 
 // Start 启动本地 session manager
-func (l *ChanManager) Start(apInfo *types.WebsocketAP, token *Token, intents int) error {
+func (l *ChanManager) Start(apInfo *types.WebsocketAP, tokenSource TokenSource, intents int) error {
 	// 计算每个 session 的启动间隔时间,避免超过频控限制
 	startInterval := utils.CalcInterval(apInfo.SessionStartLimit.MaxConcurrency)
 	log.Printf("[ws/session/local] will start %d sessions and per session start interval is %s",
@@ -76,7 +75,7 @@ func (l *ChanManager) Start(apInfo *types.WebsocketAP, token *Token, intents int
 	for i := uint32(0); i < apInfo.Shards; i++ {
 		session := Session{
 			URL:     apInfo.URL,
-			Token:   *token,
+			Token:   tokenSource,
 			Intent:  intents,
 			LastSeq: 0,
 			Shards: types.ShardConfig{
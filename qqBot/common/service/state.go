@@ -0,0 +1,134 @@
+package service
+
+import (
+	"qqbot/common/types"
+	"sort"
+	"sync"
+)
+
+// State 根据 GUILD_*、CHANNEL_* 事件维护的频道和子频道本地缓存，以及 READY 事件中机器人自身的信息
+type State struct {
+	mu       sync.RWMutex
+	me       *types.WSUser
+	guilds   map[string]*types.Guild
+	channels map[string]*types.Channel
+}
+
+// DefaultState 事件解析时更新的本地缓存，需要注册 GuildEventHandler 或 ChannelEventHandler 订阅 GUILDS 事件
+var DefaultState = NewState()
+
+// NewState 创建本地缓存
+func NewState() *State {
+	return &State{
+		guilds:   make(map[string]*types.Guild),
+		channels: make(map[string]*types.Channel),
+	}
+}
+
+// Me 获取机器人自身的信息，websocket 连接收到 READY 事件之前返回 false
+func (s *State) Me() (types.WSUser, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.me == nil {
+		return types.WSUser{}, false
+	}
+	return *s.me, true
+}
+
+// Guild 获取频道信息
+func (s *State) Guild(guildID string) (types.Guild, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	g, ok := s.guilds[guildID]
+	if !ok {
+		return types.Guild{}, false
+	}
+	return *g, true
+}
+
+// Guilds 获取所有频道，按ID排序
+func (s *State) Guilds() []types.Guild {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	guilds := make([]types.Guild, 0, len(s.guilds))
+	for _, g := range s.guilds {
+		guilds = append(guilds, *g)
+	}
+	sort.Slice(guilds, func(i, j int) bool { return guilds[i].ID < guilds[j].ID })
+	return guilds
+}
+
+// Channel 获取子频道信息
+func (s *State) Channel(channelID string) (types.Channel, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	c, ok := s.channels[channelID]
+	if !ok {
+		return types.Channel{}, false
+	}
+	return *c, true
+}
+
+// Channels 获取频道下的所有子频道，按排序值排序
+func (s *State) Channels(guildID string) []types.Channel {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var channels []types.Channel
+	for _, c := range s.channels {
+		if c.GuildID == guildID {
+			channels = append(channels, *c)
+		}
+	}
+	sort.Slice(channels, func(i, j int) bool {
+		if channels[i].Position != channels[j].Position {
+			return channels[i].Position < channels[j].Position
+		}
+		return channels[i].ID < channels[j].ID
+	})
+	return channels
+}
+
+// setMe 更新机器人自身的信息
+func (s *State) setMe(user *types.WSUser) {
+	u := *user
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.me = &u
+}
+
+// setGuild 新增或更新频道
+func (s *State) setGuild(guild *types.Guild) {
+	g := *guild
+	g.OpUserID = ""
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.guilds[g.ID] = &g
+}
+
+// removeGuild 移除频道以及频道下的所有子频道
+func (s *State) removeGuild(guildID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.guilds, guildID)
+	for id, c := range s.channels {
+		if c.GuildID == guildID {
+			delete(s.channels, id)
+		}
+	}
+}
+
+// setChannel 新增或更新子频道
+func (s *State) setChannel(channel *types.Channel) {
+	c := *channel
+	c.OpUserID = ""
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.channels[c.ID] = &c
+}
+
+// removeChannel 移除子频道
+func (s *State) removeChannel(channelID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.channels, channelID)
+}
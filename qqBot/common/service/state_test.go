@@ -0,0 +1,56 @@
+package service
+
+import (
+	"qqbot/common/types"
+	"qqbot/constant"
+	"testing"
+)
+
+// dispatch 构造分发事件并交给 ParseAndHandle 处理
+func dispatch(t *testing.T, eventType string, data string) {
+	t.Helper()
+	payload := &types.WSPayload{
+		WSPayloadBase: types.WSPayloadBase{OPCode: constant.WSDispatchEvent, Type: eventType},
+		RawMessage:    []byte(`{"op":0,"t":"` + eventType + `","d":` + data + `}`),
+	}
+	if err := ParseAndHandle(payload); err != nil {
+		t.Fatalf("handle %s failed: %v", eventType, err)
+	}
+}
+
+func TestState(t *testing.T) {
+	DefaultState = NewState()
+	var events []string
+	var guildHandler GuildEventHandler = func(event *types.WSPayload, data *types.Guild) error {
+		events = append(events, event.Type+" "+data.ID)
+		return nil
+	}
+	sub, _ := DefaultEventBus.Subscribe(guildHandler, DefaultPriority)
+	defer sub.Unsubscribe()
+
+	dispatch(t, EventGuildCreate, `{"id":"g1","name":"guild","op_user_id":"u1"}`)
+	dispatch(t, EventChannelCreate, `{"id":"c2","guild_id":"g1","name":"b","position":2}`)
+	dispatch(t, EventChannelCreate, `{"id":"c1","guild_id":"g1","name":"a","position":1}`)
+	dispatch(t, EventGuildUpdate, `{"id":"g1","name":"renamed"}`)
+	if g, ok := DefaultState.Guild("g1"); !ok || g.Name != "renamed" || g.OpUserID != "" {
+		t.Fatalf("unexpected guild %+v", g)
+	}
+	if channels := DefaultState.Channels("g1"); len(channels) != 2 || channels[0].ID != "c1" {
+		t.Fatalf("unexpected channels %+v", channels)
+	}
+
+	dispatch(t, EventChannelDelete, `{"id":"c1","guild_id":"g1"}`)
+	if _, ok := DefaultState.Channel("c1"); ok {
+		t.Fatalf("deleted channel should be removed")
+	}
+	dispatch(t, EventGuildDelete, `{"id":"g1"}`)
+	if len(DefaultState.Guilds()) != 0 || len(DefaultState.Channels("g1")) != 0 {
+		t.Fatalf("guild and its channels should be removed")
+	}
+	if len(events) != 3 || events[2] != "GUILD_DELETE g1" {
+		t.Fatalf("unexpected events %v", events)
+	}
+	if HandlerIntent(guildHandler) != constant.IntentGuilds {
+		t.Fatalf("unexpected intent")
+	}
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"qqbot/constant"
+)
+
+const (
+	// TokenTypeBot 旧版静态 token，格式为 Bot {appid}.{token}
+	TokenTypeBot = "Bot"
+	// TokenTypeQQBot 通过 AppID 和 ClientSecret 换取的 access token，格式为 QQBot {access_token}
+	TokenTypeQQBot = "QQBot"
+	// tokenRefreshAhead 在 access token 过期前多久刷新，有效期较短时最多提前有效期的一半
+	tokenRefreshAhead = 60 * time.Second
+	// tokenRetryInterval 刷新失败后至少间隔多久再重试
+	tokenRetryInterval = 5 * time.Second
+)
+
+// TokenSource 接口调用凭证的来源，HttpClient 和 websocket 鉴权都从这里获取 token
+type TokenSource interface {
+	// Token 返回当前可用的 token，需要支持并发调用
+	Token(ctx context.Context) (*Token, error)
+}
+
+// Token 用于调用接口的 token 结构
+type Token struct {
+	AppID       uint64
+	AccessToken string
+	Type        string
+}
+
+// ToStr 将 Token 转换为字符串形式，Bot 类型为 {appid}.{token}，QQBot 类型为 access token
+func (tk *Token) ToStr() string {
+	if tk.Type == TokenTypeQQBot {
+		return tk.AccessToken
+	}
+	return fmt.Sprintf("%v.%s", tk.AppID, tk.AccessToken)
+}
+
+// Authorization 返回带鉴权类型的完整凭证，用于 websocket 鉴权
+func (tk *Token) Authorization() string {
+	return tk.Type + " " + tk.ToStr()
+}
+
+// Token 静态 token 本身就是一个 TokenSource
+func (tk *Token) Token(context.Context) (*Token, error) {
+	return tk, nil
+}
+
+// appAccessTokenRsp getAppAccessToken 接口的响应，expires_in 可能是字符串也可能是数字
+type appAccessTokenRsp struct {
+	AccessToken string          `json:"access_token"`
+	ExpiresIn   json.RawMessage `json:"expires_in"`
+	Code        int             `json:"code"`
+	Message     string          `json:"message"`
+}
+
+// AppAccessTokenSource 通过 AppID 和 ClientSecret 获取 QQBot access token，
+// 缓存获取到的 token，并在过期前自动在后台刷新，并发调用时只会发起一次刷新请求，
+// 刷新失败时在 token 过期前继续使用缓存的 token
+type AppAccessTokenSource struct {
+	appID        uint64
+	clientSecret string
+	url          string
+	restyClient  *resty.Client
+
+	mu         sync.Mutex
+	token      *Token
+	refreshAt  time.Time  // 到达该时间后在后台刷新 token
+	expiresAt  time.Time  // token 过期的时间，过期后调用方需要等待刷新完成
+	refreshing *tokenCall // 正在进行的刷新请求，并发调用时共享
+	retryAt    time.Time  // 上次刷新失败后，到达该时间才能再次刷新
+	lastErr    error      // 上次刷新失败的错误
+}
+
+// tokenCall 一次刷新 token 的请求，done 关闭后 token 和 err 可读
+type tokenCall struct {
+	done  chan struct{}
+	token *Token
+	err   error
+}
+
+// NewAppAccessTokenSource 创建 access token 来源，tokenURL 为空时使用正式环境的地址
+func NewAppAccessTokenSource(appID uint64, clientSecret string, tokenURL string) *AppAccessTokenSource {
+	if tokenURL == "" {
+		tokenURL = constant.AppAccessTokenURL
+	}
+	return &AppAccessTokenSource{
+		appID:        appID,
+		clientSecret: clientSecret,
+		url:          tokenURL,
+		restyClient:  resty.New().SetTimeout(10 * time.Second),
+	}
+}
+
+// Token 返回缓存的 access token，进入提前刷新的时间段后在后台刷新并继续返回缓存的 token，
+// 没有可用的 token 时等待刷新完成，请求接口时不持有锁
+func (s *AppAccessTokenSource) Token(ctx context.Context) (*Token, error) {
+	s.mu.Lock()
+	now := time.Now()
+	if s.token != nil && now.Before(s.expiresAt) {
+		token := s.token
+		if !now.Before(s.refreshAt) {
+			s.startRefreshLocked(now)
+		}
+		s.mu.Unlock()
+		return token, nil
+	}
+	call := s.startRefreshLocked(now)
+	lastErr := s.lastErr
+	s.mu.Unlock()
+	if call == nil {
+		// 刚刚刷新失败，等待重试间隔
+		return nil, lastErr
+	}
+
+	select {
+	case <-call.done:
+		return call.token, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// startRefreshLocked 返回正在进行的刷新请求，没有时发起新的请求，处于重试间隔内时返回 nil，调用方需持有 s.mu
+func (s *AppAccessTokenSource) startRefreshLocked(now time.Time) *tokenCall {
+	if s.refreshing != nil {
+		return s.refreshing
+	}
+	if now.Before(s.retryAt) {
+		return nil
+	}
+	call := &tokenCall{done: make(chan struct{})}
+	s.refreshing = call
+	// 刷新结果由所有调用方共享，不受某个调用方取消的影响
+	go s.refresh(context.Background(), call)
+	return call
+}
+
+// refresh 获取新的 access token 并更新缓存，完成后通知等待的调用方
+func (s *AppAccessTokenSource) refresh(ctx context.Context, call *tokenCall) {
+	token, expiresIn, err := s.fetch(ctx)
+	now := time.Now()
+	s.mu.Lock()
+	if err == nil {
+		s.token = token
+		s.refreshAt = now.Add(expiresIn - refreshAhead(expiresIn))
+		s.expiresAt = now.Add(expiresIn)
+		s.retryAt, s.lastErr = time.Time{}, nil
+	} else {
+		s.retryAt, s.lastErr = now.Add(tokenRetryInterval), err
+	}
+	s.refreshing = nil
+	s.mu.Unlock()
+	call.token, call.err = token, err
+	close(call.done)
+}
+
+// refreshAhead 返回在过期前多久刷新，不超过有效期的一半，避免有效期较短时每次调用都刷新
+func refreshAhead(expiresIn time.Duration) time.Duration {
+	return min(tokenRefreshAhead, expiresIn/2)
+}
+
+// fetch 请求 getAppAccessToken 接口获取新的 access token
+func (s *AppAccessTokenSource) fetch(ctx context.Context) (*Token, time.Duration, error) {
+	resp, err := s.restyClient.R().SetContext(ctx).
+		SetBody(map[string]string{
+			"appId":        strconv.FormatUint(s.appID, 10),
+			"clientSecret": s.clientSecret,
+		}).
+		SetResult(appAccessTokenRsp{}).
+		Post(s.url)
+	if err != nil {
+		return nil, 0, err
+	}
+	result := resp.Result().(*appAccessTokenRsp)
+	if result.AccessToken == "" {
+		return nil, 0, fmt.Errorf("get app access token failed, status %d, code %d, message %s",
+			resp.StatusCode(), result.Code, result.Message)
+	}
+	expiresIn, err := strconv.Atoi(strings.Trim(string(result.ExpiresIn), `"`))
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid expires_in %s", result.ExpiresIn)
+	}
+	token := &Token{
+		AppID:       s.appID,
+		AccessToken: result.AccessToken,
+		Type:        TokenTypeQQBot,
+	}
+	return token, time.Duration(expiresIn) * time.Second, nil
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"qqbot/common/service/servicetest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAppAccessTokenSource(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("test token cached until expiry", func(t *testing.T) {
+		server := servicetest.NewTokenServer(1024, "secret", 7200)
+		defer server.Close()
+		source := NewAppAccessTokenSource(1024, "secret", server.URL)
+
+		for i := 0; i < 3; i++ {
+			token, err := source.Token(ctx)
+			if err != nil {
+				t.Fatalf("get token failed: %v", err)
+			}
+			if token.Authorization() != "QQBot "+server.TokenFor(1) {
+				t.Fatalf("unexpected token %s", token.Authorization())
+			}
+		}
+		if server.Requests() != 1 {
+			t.Fatalf("token should be cached, requested %d times", server.Requests())
+		}
+	})
+
+	t.Run("test token refreshed in background before expiry", func(t *testing.T) {
+		server := servicetest.NewTokenServer(1024, "secret", 7200)
+		defer server.Close()
+		source := NewAppAccessTokenSource(1024, "secret", server.URL)
+
+		_, _ = source.Token(ctx)
+		enterRefreshWindow(source)
+		// 刷新期间继续使用缓存的 token
+		token, err := source.Token(ctx)
+		if err != nil || token.AccessToken != server.TokenFor(1) {
+			t.Fatalf("cached token should be returned, got %v, err %v", token, err)
+		}
+		waitRefreshed(t, source)
+		if token, _ = source.Token(ctx); token.AccessToken != server.TokenFor(2) {
+			t.Fatalf("token should be refreshed, got %s", token.AccessToken)
+		}
+	})
+
+	t.Run("test refresh failure before expiry", func(t *testing.T) {
+		server := servicetest.NewTokenServer(1024, "secret", 7200)
+		defer server.Close()
+		source := NewAppAccessTokenSource(1024, "secret", server.URL)
+
+		_, _ = source.Token(ctx)
+		server.SetFailing(true)
+		enterRefreshWindow(source)
+		for i := 0; i < 3; i++ {
+			token, err := source.Token(ctx)
+			if err != nil || token.AccessToken != server.TokenFor(1) {
+				t.Fatalf("cached token should be used until expiry, got %v, err %v", token, err)
+			}
+			waitRefreshed(t, source)
+		}
+		// 重试间隔内不会再次请求
+		if server.Requests() != 2 {
+			t.Fatalf("failed refresh should not be retried immediately, requested %d times", server.Requests())
+		}
+
+		// 过期后返回刷新失败的错误
+		source.mu.Lock()
+		source.expiresAt = time.Now()
+		source.mu.Unlock()
+		if _, err := source.Token(ctx); err == nil {
+			t.Fatalf("expired token should not be returned")
+		}
+
+		server.SetFailing(false)
+		source.mu.Lock()
+		source.retryAt = time.Time{}
+		source.mu.Unlock()
+		if token, err := source.Token(ctx); err != nil || token.AccessToken != server.TokenFor(server.Requests()) {
+			t.Fatalf("token should be refreshed after recovery, got %v, err %v", token, err)
+		}
+	})
+
+	t.Run("test short-lived token cached", func(t *testing.T) {
+		// 有效期小于提前刷新的时间时，最多提前有效期的一半刷新
+		server := servicetest.NewTokenServer(1024, "secret", 30)
+		defer server.Close()
+		source := NewAppAccessTokenSource(1024, "secret", server.URL)
+
+		for i := 0; i < 3; i++ {
+			if _, err := source.Token(ctx); err != nil {
+				t.Fatalf("get token failed: %v", err)
+			}
+		}
+		if server.Requests() != 1 {
+			t.Fatalf("short-lived token should be cached, requested %d times", server.Requests())
+		}
+		if ahead := refreshAhead(30 * time.Second); ahead != 15*time.Second {
+			t.Fatalf("unexpected refresh margin %s", ahead)
+		}
+	})
+
+	t.Run("test canceled caller does not wait for refresh", func(t *testing.T) {
+		server := servicetest.NewTokenServer(1024, "secret", 7200)
+		defer server.Close()
+		source := NewAppAccessTokenSource(1024, "secret", server.URL)
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		// 刷新极快时也可能直接拿到 token
+		if _, err := source.Token(canceled); err != nil && err != context.Canceled {
+			t.Fatalf("expect canceled, got %v", err)
+		}
+		// 被取消的调用方发起的刷新仍然会完成
+		if token, err := source.Token(ctx); err != nil || token.AccessToken != server.TokenFor(1) {
+			t.Fatalf("unexpected token %v, err %v", token, err)
+		}
+	})
+
+	t.Run("test concurrent refresh", func(t *testing.T) {
+		server := servicetest.NewTokenServer(1024, "secret", 7200)
+		defer server.Close()
+		source := NewAppAccessTokenSource(1024, "secret", server.URL)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := source.Token(ctx); err != nil {
+					t.Errorf("get token failed: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+		if server.Requests() != 1 {
+			t.Fatalf("concurrent callers should share one refresh, requested %d times", server.Requests())
+		}
+	})
+
+	t.Run("test invalid secret", func(t *testing.T) {
+		server := servicetest.NewTokenServer(1024, "secret", 7200)
+		defer server.Close()
+		source := NewAppAccessTokenSource(1024, "wrong", server.URL)
+		if _, err := source.Token(ctx); err == nil {
+			t.Fatalf("invalid secret should fail")
+		}
+	})
+
+	t.Run("test static bot token", func(t *testing.T) {
+		token := &Token{AppID: 1024, AccessToken: "token", Type: TokenTypeBot}
+		if token.Authorization() != "Bot 1024.token" {
+			t.Fatalf("unexpected authorization %s", token.Authorization())
+		}
+	})
+}
+
+// enterRefreshWindow 模拟进入提前刷新的时间段
+func enterRefreshWindow(source *AppAccessTokenSource) {
+	source.mu.Lock()
+	defer source.mu.Unlock()
+	source.refreshAt = time.Now()
+}
+
+// waitRefreshed 等待后台刷新结束
+func waitRefreshed(t *testing.T, source *AppAccessTokenSource) {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		source.mu.Lock()
+		refreshing := source.refreshing != nil
+		source.mu.Unlock()
+		if !refreshing {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("background refresh not finished")
+}
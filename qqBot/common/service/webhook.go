@@ -0,0 +1,177 @@
+package service
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"qqbot/common/types"
+	"qqbot/constant"
+	"qqbot/utils"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const (
+	// HeaderSignature 回调请求中的 ed25519 签名
+	HeaderSignature = "X-Signature-Ed25519"
+	// HeaderTimestamp 回调请求中参与签名的时间戳
+	HeaderTimestamp = "X-Signature-Timestamp"
+	// maxWebhookBodySize 回调请求体的最大长度
+	maxWebhookBodySize = 1 << 20
+)
+
+// 回调服务的超时设置，服务部署在公网负载均衡之后，避免慢连接长期占用资源
+const (
+	webhookReadHeaderTimeout = 5 * time.Second
+	webhookReadTimeout       = 10 * time.Second
+	webhookWriteTimeout      = 5 * time.Second // 只需要写入 ack，事件在响应之后异步处理
+	webhookIdleTimeout       = 60 * time.Second
+)
+
+var errInvalidSignature = errors.New("invalid signature")
+
+// WebhookHandler HTTP 回调模式的事件入口，校验签名后将事件交给 ParseAndHandle 处理
+type WebhookHandler struct {
+	privateKey ed25519.PrivateKey
+}
+
+// NewWebhookHandler 使用机器人密钥创建回调处理器
+func NewWebhookHandler(secret string) (*WebhookHandler, error) {
+	privateKey, err := webhookKey(secret)
+	if err != nil {
+		return nil, err
+	}
+	return &WebhookHandler{privateKey: privateKey}, nil
+}
+
+// ListenWebhook 以 HTTP 回调模式启动服务，阻塞直到服务退出
+func ListenWebhook(addr string, secret string) error {
+	handler, err := NewWebhookHandler(secret)
+	if err != nil {
+		return err
+	}
+	log.Printf("[webhook] listening on %s", addr)
+	return newWebhookServer(addr, handler).ListenAndServe()
+}
+
+// newWebhookServer 创建设置了读写超时的回调服务
+func newWebhookServer(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: webhookReadHeaderTimeout,
+		ReadTimeout:       webhookReadTimeout,
+		WriteTimeout:      webhookWriteTimeout,
+		IdleTimeout:       webhookIdleTimeout,
+	}
+}
+
+// webhookKey 根据机器人密钥生成 ed25519 私钥，密钥重复拼接至 seed 长度后截断作为 seed
+func webhookKey(secret string) (ed25519.PrivateKey, error) {
+	if secret == "" {
+		return nil, errors.New("webhook secret is empty")
+	}
+	seed := secret
+	for len(seed) < ed25519.SeedSize {
+		seed = strings.Repeat(seed, 2)
+	}
+	return ed25519.NewKeyFromSeed([]byte(seed[:ed25519.SeedSize])), nil
+}
+
+// ServeHTTP 处理回调请求
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBodySize))
+	if err != nil {
+		log.Printf("[webhook] read body failed, %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if err = h.verify(r.Header, body); err != nil {
+		log.Printf("[webhook] verify failed, %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	payload := &types.WSPayload{}
+	if err = json.Unmarshal(body, payload); err != nil {
+		log.Printf("[webhook] json failed, %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	payload.RawMessage = body
+	log.Printf("[webhook] receive %s message, %s", utils.GetOpMeans(payload.OPCode), string(body))
+
+	switch payload.OPCode {
+	case constant.HTTPCallbackValidation: // 配置回调地址时的验证请求
+		h.validationHandler(w, payload)
+	case constant.WSDispatchEvent:
+		// 先回复 ack，业务处理放到协程中，避免耗时的 handler 导致平台重推
+		writeJSON(w, &types.WHCallbackAck{OPCode: constant.HTTPCallbackAck})
+		go handleWebhookEvent(payload)
+	default:
+		writeJSON(w, &types.WHCallbackAck{OPCode: constant.HTTPCallbackAck})
+	}
+}
+
+// verify 校验请求签名，签名内容为 timestamp+body
+func (h *WebhookHandler) verify(header http.Header, body []byte) error {
+	sig, err := hex.DecodeString(header.Get(HeaderSignature))
+	// 合法签名最后一个字节的高 3 位必须为 0
+	if err != nil || len(sig) != ed25519.SignatureSize || sig[63]&224 != 0 {
+		return errInvalidSignature
+	}
+	var msg bytes.Buffer
+	msg.WriteString(header.Get(HeaderTimestamp))
+	msg.Write(body)
+	if !ed25519.Verify(h.privateKey.Public().(ed25519.PublicKey), msg.Bytes(), sig) {
+		return errInvalidSignature
+	}
+	return nil
+}
+
+// validationHandler 回调地址验证，对 event_ts+plain_token 签名后返回
+func (h *WebhookHandler) validationHandler(w http.ResponseWriter, payload *types.WSPayload) {
+	req := &types.WHValidationReq{}
+	if err := utils.ParseData(payload.RawMessage, req); err != nil {
+		log.Printf("[webhook] validation data parse failed, %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	sig := ed25519.Sign(h.privateKey, []byte(req.EventTs+req.PlainToken))
+	writeJSON(w, &types.WHValidationRsp{
+		PlainToken: req.PlainToken,
+		Signature:  hex.EncodeToString(sig),
+	})
+}
+
+// handleWebhookEvent 解析事件并投递给业务注册的 handler
+func handleWebhookEvent(payload *types.WSPayload) {
+	defer func() {
+		if err := recover(); err != nil {
+			buf := make([]byte, 1024)
+			buf = buf[:runtime.Stack(buf, false)]
+			log.Printf("[PANIC][webhook]%v\n%s\n", err, buf)
+		}
+	}()
+	if err := ParseAndHandle(payload); err != nil {
+		log.Printf("[webhook] parseAndHandle failed, %v", err)
+	}
+}
+
+// writeJSON 以 json 格式写回响应
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("[webhook] write response failed, %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"qqbot/common/types"
+	"strings"
+	"testing"
+	"time"
+)
+
+// signedRequest 构造带签名的回调请求
+func signedRequest(key ed25519.PrivateKey, body string) *http.Request {
+	timestamp := "1725442341"
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, hex.EncodeToString(ed25519.Sign(key, []byte(timestamp+body))))
+	return req
+}
+
+func TestWebhook(t *testing.T) {
+	const secret = "naOC0ocQE3shWLAfffVLB1rhYPG7"
+	handler, err := NewWebhookHandler(secret)
+	if err != nil {
+		t.Fatalf("create handler failed: %v", err)
+	}
+	key, _ := webhookKey(secret)
+
+	t.Run("test validation challenge", func(t *testing.T) {
+		body := `{"d":{"plain_token":"Arq0D5A61EgUu4OxUvOp","event_ts":"1725442341"},"op":13}`
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, signedRequest(key, body))
+		if w.Code != http.StatusOK {
+			t.Fatalf("unexpected status %d", w.Code)
+		}
+		rsp := &types.WHValidationRsp{}
+		if err := json.Unmarshal(w.Body.Bytes(), rsp); err != nil {
+			t.Fatalf("unexpected response %s", w.Body.String())
+		}
+		sig, _ := hex.DecodeString(rsp.Signature)
+		if rsp.PlainToken != "Arq0D5A61EgUu4OxUvOp" ||
+			!ed25519.Verify(key.Public().(ed25519.PublicKey), []byte("1725442341Arq0D5A61EgUu4OxUvOp"), sig) {
+			t.Fatalf("unexpected validation response %+v", rsp)
+		}
+	})
+
+	t.Run("test invalid signature rejected", func(t *testing.T) {
+		body := `{"op":0,"t":"AT_MESSAGE_CREATE","d":{"content":"hi"}}`
+		req := signedRequest(key, body)
+		req.Header.Set(HeaderTimestamp, "0")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("expect 401, got %d", w.Code)
+		}
+	})
+
+	t.Run("test dispatch event handled", func(t *testing.T) {
+		received := make(chan string, 1)
+		var atMessage ATMessageEventHandler = func(event *types.WSPayload, data *types.Message) error {
+			received <- data.Content
+			return nil
+		}
+		sub, _ := DefaultEventBus.Subscribe(atMessage, DefaultPriority)
+		defer sub.Unsubscribe()
+
+		body := `{"op":0,"s":1,"t":"AT_MESSAGE_CREATE","d":{"content":"hi"}}`
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, signedRequest(key, body))
+		if strings.TrimSpace(w.Body.String()) != `{"op":12}` {
+			t.Fatalf("unexpected ack %s", w.Body.String())
+		}
+		select {
+		case content := <-received:
+			if content != "hi" {
+				t.Fatalf("unexpected content %q", content)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("handler not called")
+		}
+	})
+
+	t.Run("test server timeouts set", func(t *testing.T) {
+		server := newWebhookServer(":0", handler)
+		if server.ReadHeaderTimeout <= 0 || server.ReadTimeout <= 0 || server.WriteTimeout <= 0 || server.IdleTimeout <= 0 {
+			t.Fatalf("server timeouts should be set, got %+v", server)
+		}
+	})
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,6 +11,8 @@ import (
 	"qqbot/common/types"
 	constant "qqbot/constant"
 	"qqbot/utils"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -18,6 +21,23 @@ import (
 
 var ResumeSignal syscall.Signal
 
+var (
+	// errNeedReconnect 服务端要求重连，或收到重连信号，可以通过 resume 续传原连接上的事件
+	errNeedReconnect = errors.New("need reconnect")
+	// errInvalidSession session 已失效，需要重新鉴权
+	errInvalidSession = errors.New("invalid session")
+	// errHeartbeatTimeout 上一次心跳没有收到 ack，连接可能已经失效，需要 resume 重连
+	errHeartbeatTimeout = errors.New("heartbeat ack timeout")
+	// errConnClosed 连接已关闭，无法再发送数据
+	errConnClosed = errors.New("websocket connection closed")
+)
+
+const (
+	writeWait       = 10 * time.Second // 单次写入的超时时间
+	closeWait       = 3 * time.Second  // 发送 close 帧后等待服务端回应的时间
+	outboundBufSize = 100              // 待发送消息队列长度
+)
+
 //type messageChan chan *types.WSPayload
 //type closeErrorChan chan error
 
@@ -25,12 +45,18 @@ var ResumeSignal syscall.Signal
 type Session struct {
 	ID      string
 	URL     string
-	Token   Token
+	Token   TokenSource
 	Intent  int
 	LastSeq uint32
 	Shards  types.ShardConfig
 }
 
+// String 输出 session 的日志标识
+func (s Session) String() string {
+	return fmt.Sprintf("[ws/session][ID:%s][Shard:(%d/%d)][Intent:%d]",
+		s.ID, s.Shards.ShardID, s.Shards.ShardCount, s.Intent)
+}
+
 // WebsocketClient Client websocket 连接客户端
 type WebsocketClient struct {
 	Version         int
@@ -39,7 +65,18 @@ type WebsocketClient struct {
 	Session         *Session
 	User            *types.WSUser
 	CloseChan       types.CloseErrorChan
-	HeartBeatTicker *time.Ticker // 用于维持定时心跳
+	HeartBeatTicker *time.Ticker  // 用于维持定时心跳
+	sessionLock     sync.RWMutex  // 保护 Session，读协程会根据 READY、RESUMED 等事件更新 session
+	heartbeatSentAt atomic.Int64  // 最近一次未被 ack 的心跳发送时间(UnixNano)，收到 ack 后清零
+	lastAckAt       atomic.Int64  // 最近一次收到心跳 ack 的时间(UnixNano)
+	latency         atomic.Int64  // 最近一次心跳的往返时延
+	outbound        chan []byte   // 待发送的消息，由写协程串行写入连接
+	heartbeat       chan []byte   // 待发送的心跳，优先于 outbound 写入，避免排队导致心跳超时
+	closing         chan struct{} // 关闭连接时 close，通知写协程发送 close 帧后退出
+	writerDone      chan struct{} // 写协程退出时 close
+	readerDone      chan struct{} // 读协程退出时 close
+	reading         atomic.Bool   // 读协程是否已经启动
+	closeOnce       sync.Once
 }
 
 // NewWebsocket 创建一个新的 ws 实例，需要传递 session 对象
@@ -49,6 +86,11 @@ func NewWebsocket(session Session) *WebsocketClient {
 		Session:         &session,
 		CloseChan:       make(types.CloseErrorChan, 10),
 		HeartBeatTicker: time.NewTicker(60 * time.Second), // 先给一个默认 ticker，在收到 hello 包之后，会 reset
+		outbound:        make(chan []byte, outboundBufSize),
+		heartbeat:       make(chan []byte, 1),
+		closing:         make(chan struct{}),
+		writerDone:      make(chan struct{}),
+		readerDone:      make(chan struct{}),
 	}
 }
 
@@ -61,10 +103,12 @@ func (c *WebsocketClient) Connect() error {
 	var err error
 	c.Conn, _, err = wss.DefaultDialer.Dial(c.Session.URL, nil)
 	if err != nil {
-		log.Printf("%s, connect err: %v", c.Session, err)
+		log.Printf("%s, connect err: %v", c, err)
 		return err
 	}
-	log.Printf("%s, url %s, connected", c.Session, c.Session.URL)
+	log.Printf("%s, url %s, connected", c, c.Session.URL)
+	// gorilla/websocket 不支持并发写，所有写操作都交给写协程串行执行
+	go c.writeLoop()
 	return nil
 }
 
@@ -74,9 +118,13 @@ func (c *WebsocketClient) Identify() error {
 	if c.Session.Intent == 0 {
 		c.Session.Intent = 1
 	}
+	token, err := c.Session.Token.Token(context.Background())
+	if err != nil {
+		return err
+	}
 	payload := &types.WSPayload{
 		Data: &types.WSIdentityData{
-			Token:   c.Session.Token.ToStr(),
+			Token:   token.Authorization(),
 			Intents: c.Session.Intent,
 			Shard: []uint32{
 				c.Session.Shards.ShardID,
@@ -88,18 +136,31 @@ func (c *WebsocketClient) Identify() error {
 	return c.SendMessage(payload)
 }
 
-// GetSession 拉取 session 信息，包括 token，shard，seq 等
+// GetSession 拉取 session 信息，包括 token，shard，seq 等，返回的是当前 session 的副本
 func (c *WebsocketClient) GetSession() *Session {
-	return c.Session
+	c.sessionLock.RLock()
+	defer c.sessionLock.RUnlock()
+	session := *c.Session
+	return &session
 }
 
-// ReTry 重连
+// String 输出连接的日志标识
+func (c *WebsocketClient) String() string {
+	return c.GetSession().String()
+}
+
+// ReTry 重连，使用 session ID 和最后收到的 seq 续传断线期间的事件，成功后服务端会下发 RESUMED 事件
 func (c *WebsocketClient) ReTry() error {
+	session := c.GetSession()
+	token, err := session.Token.Token(context.Background())
+	if err != nil {
+		return err
+	}
 	payload := &types.WSPayload{
 		Data: &types.WSResumeData{
-			Token:     c.Session.Token.ToStr(),
-			SessionID: c.Session.ID,
-			Seq:       c.Session.LastSeq,
+			Token:     token.Authorization(),
+			SessionID: session.ID,
+			Seq:       session.LastSeq,
 		},
 	}
 	payload.OPCode = constant.WSReTry // 内嵌结构体字段，单独赋值
@@ -110,6 +171,7 @@ func (c *WebsocketClient) ReTry() error {
 func (c *WebsocketClient) Listening() error {
 	defer c.Close()
 	// 读取消息到队列
+	c.reading.Store(true)
 	go c.readMessageToQueue()
 	// 从队列读取消息并处理，在 goroutine 中执行以避免业务逻辑阻塞 closeChan 和 heartBeatTicker
 	go c.listenMessageAndHandle()
@@ -124,62 +186,171 @@ func (c *WebsocketClient) Listening() error {
 	for {
 		select {
 		case <-resumeSignal: // 使用信号量控制连接立即重连
-			log.Printf("%s, received resumeSignal signal", c.Session)
-			return errors.New("need reconnect")
+			log.Printf("%s, received resumeSignal signal", c)
+			return errNeedReconnect
 		case err := <-c.CloseChan:
-			log.Printf("%v Listening stop. err is %v", c.Session, err)
-			if wss.IsCloseError(err, 4914, 4915) {
-				err = errors.New(err.Error())
-			}
-			if wss.IsUnexpectedCloseError(err, 4009) {
-				err = errors.New(err.Error())
-			}
+			log.Printf("%s Listening stop. err is %v", c, err)
 			return err
 		case <-c.HeartBeatTicker.C:
-			log.Printf("%v listened heartBeat", c.Session)
+			log.Printf("%s listened heartBeat", c)
+			// 上一次心跳还没有收到 ack，认为连接已经僵死，退出后通过 resume 重连
+			if sentAt := c.heartbeatSentAt.Load(); sentAt != 0 {
+				log.Printf("%s heartbeat sent at %s not acked", c, time.Unix(0, sentAt).Format(time.RFC3339))
+				return errHeartbeatTimeout
+			}
 			heartBeatEvent := &types.WSPayload{
 				WSPayloadBase: types.WSPayloadBase{
 					OPCode: constant.WSHeartbeat,
 				},
-				Data: c.Session.LastSeq,
+				Data: c.GetSession().LastSeq,
 			}
+			c.heartbeatSentAt.Store(time.Now().UnixNano())
 			// 不处理错误，Write 内部会处理，如果发生发包异常，会通知主协程退出
-			_ = c.SendMessage(heartBeatEvent)
+			_ = c.sendHeartbeat(heartBeatEvent)
 		}
 	}
 }
 
-// SendMessage 发送数据
+// SendMessage 发送数据，消息进入发送队列后由写协程写入连接，可以在任意协程中调用
 func (c *WebsocketClient) SendMessage(message *types.WSPayload) error {
-	m, _ := json.Marshal(message)
-	log.Printf("%v write %s message, %v", c.Session, utils.GetOpMeans(message.OPCode), string(m))
+	m, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	log.Printf("%s write %s message, %v", c, utils.GetOpMeans(message.OPCode), string(m))
 
-	if err := c.Conn.WriteMessage(wss.TextMessage, m); err != nil {
-		log.Printf("%s WriteMessage failed, %v", c.Session, err)
-		c.CloseChan <- err
+	// 写协程已经退出时直接返回，避免阻塞在发送队列上
+	select {
+	case <-c.writerDone:
+		return errConnClosed
+	default:
+	}
+	select {
+	case c.outbound <- m:
+		return nil
+	case <-c.writerDone:
+		return errConnClosed
+	}
+}
+
+// sendHeartbeat 发送心跳，心跳不进入普通发送队列，由写协程优先写入，
+// 还有未写入的心跳时用新的心跳替换它
+func (c *WebsocketClient) sendHeartbeat(message *types.WSPayload) error {
+	m, err := json.Marshal(message)
+	if err != nil {
 		return err
 	}
-	return nil
+	log.Printf("%s write %s message, %v", c, utils.GetOpMeans(message.OPCode), string(m))
+
+	select {
+	case <-c.writerDone:
+		return errConnClosed
+	default:
+	}
+	for {
+		select {
+		case c.heartbeat <- m:
+			return nil
+		default:
+		}
+		select {
+		case <-c.heartbeat:
+		default:
+		}
+	}
 }
 
-// Close 关闭连接
+// writeLoop 写协程，串行地将发送队列中的消息写入连接，心跳优先写入，关闭时发送 close 帧完成关闭握手
+func (c *WebsocketClient) writeLoop() {
+	defer close(c.writerDone)
+	for {
+		// 先检查心跳，避免 outbound 中积压的消息推迟心跳
+		select {
+		case m := <-c.heartbeat:
+			if !c.write(m) {
+				return
+			}
+			continue
+		default:
+		}
+		select {
+		case m := <-c.heartbeat:
+			if !c.write(m) {
+				return
+			}
+		case m := <-c.outbound:
+			if !c.write(m) {
+				return
+			}
+		case <-c.closing:
+			// 先把队列中已经提交的消息发送完，再发送 close 帧
+			for len(c.outbound) > 0 {
+				if !c.write(<-c.outbound) {
+					return
+				}
+			}
+			closeMessage := wss.FormatCloseMessage(wss.CloseNormalClosure, "")
+			if err := c.Conn.WriteControl(wss.CloseMessage, closeMessage, time.Now().Add(writeWait)); err != nil {
+				log.Printf("%s write close message failed, %v", c, err)
+			}
+			return
+		}
+	}
+}
+
+// write 将消息写入连接，写入失败时通知主协程关闭连接并返回 false
+func (c *WebsocketClient) write(m []byte) bool {
+	// 设置写超时，避免慢写一直阻塞后续的心跳
+	_ = c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
+	if err := c.Conn.WriteMessage(wss.TextMessage, m); err != nil {
+		log.Printf("%s WriteMessage failed, %v", c, err)
+		c.notifyClose(err)
+		return false
+	}
+	return true
+}
+
+// Close 关闭连接，先发送 close 帧并等待服务端回应，再关闭底层连接
 func (c *WebsocketClient) Close() {
-	if err := c.Conn.Close(); err != nil {
-		log.Printf("%s, close conn err: %v", c.Session, err)
+	c.closeOnce.Do(func() {
+		c.HeartBeatTicker.Stop()
+		if c.Conn == nil {
+			return
+		}
+		close(c.closing)
+		<-c.writerDone
+		// 读协程收到服务端的 close 帧后退出，超时则直接关闭连接
+		if c.reading.Load() {
+			select {
+			case <-c.readerDone:
+			case <-time.After(closeWait):
+			}
+		}
+		if err := c.Conn.Close(); err != nil {
+			log.Printf("%s, close conn err: %v", c, err)
+		}
+	})
+}
+
+// notifyClose 通知主协程关闭连接，CloseChan 已满时说明已经有错误在等待处理，直接丢弃
+func (c *WebsocketClient) notifyClose(err error) {
+	select {
+	case c.CloseChan <- err:
+	default:
 	}
-	c.HeartBeatTicker.Stop()
 }
 
 // readMessageToQueue 从 WebSocket 连接中读取消息,解析并投递到消息队列
 func (c *WebsocketClient) readMessageToQueue() {
+	defer close(c.readerDone)
 	for {
 		// 从 WebSocket 连接中读取消息
 		_, message, err := c.Conn.ReadMessage()
 		if err != nil {
 			// 读取消息失败,打印错误日志,关闭消息队列,并通知关闭连接
-			log.Printf("%s read message failed, %v, message %s", c.Session, err, string(message))
+			log.Printf("%s read message failed, %v, message %s", c, err, string(message))
 			close(c.MessageQueue)
-			c.CloseChan <- err
+			c.notifyClose(err)
 			return
 		}
 
@@ -187,11 +358,17 @@ func (c *WebsocketClient) readMessageToQueue() {
 		payload := &types.WSPayload{}
 		if err := json.Unmarshal(message, payload); err != nil {
 			// 消息解析失败,打印错误日志并继续下一个消息
-			log.Printf("%s json failed, %v", c.Session, err)
+			log.Printf("%s json failed, %v", c, err)
 			continue
 		}
 		payload.RawMessage = message
-		log.Printf("%s receive %s message, %s", c.Session, utils.GetOpMeans(payload.OPCode), string(message))
+		log.Printf("%s receive %s message, %s", c, utils.GetOpMeans(payload.OPCode), string(message))
+		// 在读协程中记录 seq，保证断线时 resume 使用的是已收到的最后一个事件
+		if payload.Seq > 0 {
+			c.sessionLock.Lock()
+			c.Session.LastSeq = payload.Seq
+			c.sessionLock.Unlock()
+		}
 
 		// 处理内置的一些事件,如果处理成功,则不再投递给业务
 		if c.isHandleBuildIn(payload) {
@@ -205,28 +382,20 @@ func (c *WebsocketClient) readMessageToQueue() {
 // listenMessageAndHandle WebSocket 消息队列中读取事件,根据事件类型进行相应的处理,包括捕获可能发生的异常并进行重连
 func (c *WebsocketClient) listenMessageAndHandle() {
 	defer func() {
-		// panic，一般是由于业务自己实现的 handle 不完善导致
+		// handler 的 panic 已由 DefaultEventBus 的 Recovery 捕获，这里处理的是事件解析等流程中的 panic
 		// 打印日志后，关闭这个连接，进入重连流程
 		if err := recover(); err != nil {
-			PanicHandler(err, c.Session)
-			c.CloseChan <- fmt.Errorf("panic: %v", err)
+			PanicHandler(err, c.GetSession())
+			c.notifyClose(fmt.Errorf("panic: %v", err))
 		}
 	}()
 	for payload := range c.MessageQueue {
-		if payload.Seq > 0 {
-			c.Session.LastSeq = payload.Seq
-		}
-		// ready 事件需要特殊处理
-		if payload.Type == "READY" {
-			c.readyHandler(payload)
-			continue
-		}
 		// 解析具体事件，并投递给业务注册的 handler
 		if err := ParseAndHandle(payload); err != nil {
-			log.Printf("%s parseAndHandle failed, %v", c.Session, err)
+			log.Printf("%s parseAndHandle failed, %v", c, err)
 		}
 	}
-	log.Printf("%s message queue is closed", c.Session)
+	log.Printf("%s message queue is closed", c)
 }
 
 // isHandleBuildIn 内置的事件处理，处理那些不需要业务方处理的事件
@@ -235,11 +404,26 @@ func (c *WebsocketClient) isHandleBuildIn(payload *types.WSPayload) bool {
 	switch payload.OPCode {
 	case constant.WSHello: // 接收到 hello 后需要开始发心跳
 		c.startHeartBeatTicker(payload.RawMessage)
-	case constant.WSHeartbeatAck: // 心跳 ack 不需要业务处理
+	case constant.WSHeartbeatAck: // 心跳 ack 不需要业务处理，记录 ack 时间和往返时延
+		c.heartbeatAckHandler()
 	case constant.WSReconnect: // 达到连接时长，需要重新连接，此时可以通过 resume 续传原连接上的事件
-		c.CloseChan <- errors.New("need reconnect")
-	case constant.WSInvalidSession: // 无效的 sessionLog，需要重新鉴权
-		c.CloseChan <- errors.New("invalid session")
+		c.notifyClose(errNeedReconnect)
+	case constant.WSInvalidSession: // 无效的 session，清空 session 信息，下次连接重新鉴权
+		c.sessionLock.Lock()
+		c.Session.ID = ""
+		c.Session.LastSeq = 0
+		c.sessionLock.Unlock()
+		c.notifyClose(errInvalidSession)
+	case constant.WSDispatchEvent:
+		// ready 和 resumed 事件需要在读协程中更新 session，避免与重连时读取的 session 不一致
+		switch payload.Type {
+		case "READY":
+			c.readyHandler(payload)
+		case "RESUMED":
+			log.Printf("%s resumed, continue from seq %d", c, c.GetSession().LastSeq)
+		default:
+			return false
+		}
 	default:
 		return false
 	}
@@ -250,26 +434,78 @@ func (c *WebsocketClient) isHandleBuildIn(payload *types.WSPayload) bool {
 func (c *WebsocketClient) startHeartBeatTicker(message []byte) {
 	helloData := &types.WSHelloData{}
 	if err := utils.ParseData(message, helloData); err != nil {
-		log.Printf("%s hello data parse failed, %v, message %v", c.Session, err, message)
+		log.Printf("%s hello data parse failed, %v, message %v", c, err, message)
 	}
 	// 根据 hello 的回包，重新设置心跳的定时器时间
 	c.HeartBeatTicker.Reset(time.Duration(helloData.HeartbeatInterval) * time.Millisecond)
 }
 
+// heartbeatAckHandler 记录心跳 ack 的时间，并根据对应心跳的发送时间计算时延
+func (c *WebsocketClient) heartbeatAckHandler() {
+	now := time.Now()
+	if sentAt := c.heartbeatSentAt.Swap(0); sentAt != 0 {
+		c.latency.Store(int64(now.Sub(time.Unix(0, sentAt))))
+	}
+	c.lastAckAt.Store(now.UnixNano())
+}
+
+// Latency 返回最近一次心跳的往返时延，还没有收到过 ack 时返回 0
+func (c *WebsocketClient) Latency() time.Duration {
+	return time.Duration(c.latency.Load())
+}
+
+// LastHeartbeatAck 返回最近一次收到心跳 ack 的时间，还没有收到过 ack 时返回零值
+func (c *WebsocketClient) LastHeartbeatAck() time.Time {
+	at := c.lastAckAt.Load()
+	if at == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, at)
+}
+
 // readyHandler 针对ready返回的处理，需要记录 sessionID 等相关信息
 func (c *WebsocketClient) readyHandler(payload *types.WSPayload) {
 	readyData := &types.WSReadyData{}
 	if err := utils.ParseData(payload.RawMessage, readyData); err != nil {
-		log.Printf("%v parseReadyData failed, %v, message %v", c.Session, err, payload.RawMessage)
+		log.Printf("%s parseReadyData failed, %v, message %v", c, err, payload.RawMessage)
+		return
 	}
 	c.Version = readyData.Version
 	// 基于 ready 事件，更新 session 信息
+	c.sessionLock.Lock()
 	c.Session.ID = readyData.SessionID
-	c.Session.Shards.ShardID = readyData.Shard[0]
-	c.Session.Shards.ShardCount = readyData.Shard[1]
+	if len(readyData.Shard) == 2 {
+		c.Session.Shards.ShardID = readyData.Shard[0]
+		c.Session.Shards.ShardCount = readyData.Shard[1]
+	}
+	c.sessionLock.Unlock()
 	c.User = &types.WSUser{
 		ID:       readyData.User.ID,
 		Username: readyData.User.Username,
 		Bot:      readyData.User.Bot,
 	}
+	DefaultState.setMe(c.User)
+}
+
+// CanNotResume 判断连接断开的原因是否导致 session 无法续传，需要清空 session 信息后重新鉴权
+func CanNotResume(err error) bool {
+	if errors.Is(err, errInvalidSession) {
+		return true
+	}
+	// 4006 无效的 session id，4007 seq 错误，4900~4913 内部错误，均需要重新 identify
+	var closeErr *wss.CloseError
+	if errors.As(err, &closeErr) {
+		return closeErr.Code == 4006 || closeErr.Code == 4007 ||
+			(closeErr.Code >= 4900 && closeErr.Code <= 4913)
+	}
+	return false
+}
+
+// CanNotIdentify 判断连接断开的原因是否导致无法再次鉴权，4914 机器人已下架，4915 机器人已封禁
+func CanNotIdentify(err error) bool {
+	var closeErr *wss.CloseError
+	if errors.As(err, &closeErr) {
+		return closeErr.Code == 4914 || closeErr.Code == 4915
+	}
+	return false
 }
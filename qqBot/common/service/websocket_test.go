@@ -0,0 +1,222 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"qqbot/common/types"
+	"qqbot/constant"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	wss "github.com/gorilla/websocket"
+)
+
+// fakeGateway 模拟 websocket 网关，script 中按顺序与客户端交互
+func fakeGateway(t *testing.T, script func(conn *wss.Conn)) *httptest.Server {
+	upgrader := wss.Upgrader{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade failed: %v", err)
+			return
+		}
+		defer conn.Close()
+		script(conn)
+	}))
+}
+
+// readOp 读取客户端发送的一个 payload
+func readOp(t *testing.T, conn *wss.Conn) *types.WSPayload {
+	_, message, err := conn.ReadMessage()
+	if err != nil {
+		t.Errorf("read client message failed: %v", err)
+		return &types.WSPayload{}
+	}
+	payload := &types.WSPayload{}
+	_ = json.Unmarshal(message, payload)
+	return payload
+}
+
+func wsURL(server *httptest.Server) string {
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func TestWebsocketSession(t *testing.T) {
+	t.Run("test ready updates session and reconnect keeps it", func(t *testing.T) {
+		server := fakeGateway(t, func(conn *wss.Conn) {
+			_ = conn.WriteMessage(wss.TextMessage, []byte(`{"op":10,"d":{"heartbeat_interval":45000}}`))
+			if op := readOp(t, conn).OPCode; op != constant.WSIdentity {
+				t.Errorf("expect identify, got op %d", op)
+			}
+			_ = conn.WriteMessage(wss.TextMessage,
+				[]byte(`{"op":0,"s":1,"t":"READY","d":{"version":1,"session_id":"sid","user":{"id":"bot"},"shard":[0,1]}}`))
+			_ = conn.WriteMessage(wss.TextMessage, []byte(`{"op":0,"s":2,"t":"UNKNOWN_EVENT","d":{}}`))
+			_ = conn.WriteMessage(wss.TextMessage, []byte(`{"op":7}`))
+			time.Sleep(100 * time.Millisecond)
+		})
+		defer server.Close()
+
+		client := NewWebsocket(Session{URL: wsURL(server), Token: &Token{AppID: 1, AccessToken: "t", Type: TokenTypeBot}})
+		if err := client.Connect(); err != nil {
+			t.Fatalf("connect failed: %v", err)
+		}
+		if err := client.Identify(); err != nil {
+			t.Fatalf("identify failed: %v", err)
+		}
+		err := client.Listening()
+		if CanNotResume(err) {
+			t.Fatalf("reconnect should be resumable, got %v", err)
+		}
+		session := client.GetSession()
+		if session.ID != "sid" || session.LastSeq != 2 {
+			t.Fatalf("unexpected session %+v", session)
+		}
+		if me, ok := DefaultState.Me(); !ok || me.ID != "bot" {
+			t.Fatalf("ready should record the bot user, got %+v", me)
+		}
+	})
+
+	t.Run("test invalid session clears session", func(t *testing.T) {
+		server := fakeGateway(t, func(conn *wss.Conn) {
+			if op := readOp(t, conn).OPCode; op != constant.WSReTry {
+				t.Errorf("expect resume, got op %d", op)
+			}
+			_ = conn.WriteMessage(wss.TextMessage, []byte(`{"op":9,"d":false}`))
+			time.Sleep(100 * time.Millisecond)
+		})
+		defer server.Close()
+
+		client := NewWebsocket(Session{URL: wsURL(server), ID: "sid", LastSeq: 10, Token: &Token{}})
+		if err := client.Connect(); err != nil {
+			t.Fatalf("connect failed: %v", err)
+		}
+		if err := client.ReTry(); err != nil {
+			t.Fatalf("resume failed: %v", err)
+		}
+		err := client.Listening()
+		if !CanNotResume(err) {
+			t.Fatalf("invalid session should not be resumable, got %v", err)
+		}
+		if session := client.GetSession(); session.ID != "" || session.LastSeq != 0 {
+			t.Fatalf("session should be cleared, got %+v", session)
+		}
+	})
+
+	t.Run("test heartbeat ack missed", func(t *testing.T) {
+		server := fakeGateway(t, func(conn *wss.Conn) {
+			_ = conn.WriteMessage(wss.TextMessage, []byte(`{"op":10,"d":{"heartbeat_interval":50}}`))
+			// 只回复第一个心跳，之后不再 ack
+			if op := readOp(t, conn).OPCode; op != constant.WSHeartbeat {
+				t.Errorf("expect heartbeat, got op %d", op)
+			}
+			_ = conn.WriteMessage(wss.TextMessage, []byte(`{"op":11}`))
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		})
+		defer server.Close()
+
+		client := NewWebsocket(Session{URL: wsURL(server)})
+		if err := client.Connect(); err != nil {
+			t.Fatalf("connect failed: %v", err)
+		}
+		err := client.Listening()
+		if err != errHeartbeatTimeout || CanNotResume(err) {
+			t.Fatalf("expect resumable heartbeat timeout, got %v", err)
+		}
+		if client.Latency() <= 0 || client.LastHeartbeatAck().IsZero() {
+			t.Fatalf("latency should be recorded, got %v", client.Latency())
+		}
+	})
+
+	t.Run("test concurrent send and close handshake", func(t *testing.T) {
+		const senders = 20
+		closeCode := make(chan int, 1)
+		server := fakeGateway(t, func(conn *wss.Conn) {
+			for i := 0; i < senders; i++ {
+				if op := readOp(t, conn).OPCode; op != constant.WSHeartbeat {
+					t.Errorf("expect heartbeat, got op %d", op)
+				}
+			}
+			_, _, err := conn.ReadMessage()
+			if closeErr, ok := err.(*wss.CloseError); ok {
+				closeCode <- closeErr.Code
+			}
+			close(closeCode)
+		})
+		defer server.Close()
+
+		client := NewWebsocket(Session{URL: wsURL(server)})
+		if err := client.Connect(); err != nil {
+			t.Fatalf("connect failed: %v", err)
+		}
+		var wg sync.WaitGroup
+		for i := 0; i < senders; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				payload := &types.WSPayload{WSPayloadBase: types.WSPayloadBase{OPCode: constant.WSHeartbeat}}
+				if err := client.SendMessage(payload); err != nil {
+					t.Errorf("send failed: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+		client.Close()
+		if code := <-closeCode; code != wss.CloseNormalClosure {
+			t.Fatalf("expect normal closure, got %d", code)
+		}
+		if err := client.SendMessage(&types.WSPayload{}); err != errConnClosed {
+			t.Fatalf("send after close should fail, got %v", err)
+		}
+	})
+
+	t.Run("test heartbeat written before queued messages", func(t *testing.T) {
+		const queued = 50
+		ops := make(chan int, queued+1)
+		server := fakeGateway(t, func(conn *wss.Conn) {
+			for i := 0; i <= queued; i++ {
+				ops <- readOp(t, conn).OPCode
+			}
+			close(ops)
+		})
+		defer server.Close()
+
+		client := NewWebsocket(Session{URL: wsURL(server)})
+		// 写协程启动前先积压普通消息
+		for i := 0; i < queued; i++ {
+			_ = client.SendMessage(&types.WSPayload{WSPayloadBase: types.WSPayloadBase{OPCode: constant.WSIdentity}})
+		}
+		_ = client.sendHeartbeat(&types.WSPayload{WSPayloadBase: types.WSPayloadBase{OPCode: constant.WSHeartbeat}})
+		_ = client.sendHeartbeat(&types.WSPayload{WSPayloadBase: types.WSPayloadBase{OPCode: constant.WSHeartbeat}})
+		if err := client.Connect(); err != nil {
+			t.Fatalf("connect failed: %v", err)
+		}
+		defer client.Close()
+		if op := <-ops; op != constant.WSHeartbeat {
+			t.Fatalf("heartbeat should be written first, got op %d", op)
+		}
+		for op := range ops {
+			if op != constant.WSIdentity {
+				t.Fatalf("replaced heartbeat should not be written again, got op %d", op)
+			}
+		}
+	})
+
+	t.Run("test close code classification", func(t *testing.T) {
+		if CanNotResume(&wss.CloseError{Code: 4009}) {
+			t.Fatalf("4009 should be resumable")
+		}
+		if !CanNotResume(&wss.CloseError{Code: 4006}) {
+			t.Fatalf("4006 should not be resumable")
+		}
+		if !CanNotIdentify(&wss.CloseError{Code: 4914}) {
+			t.Fatalf("4914 should not be able to identify")
+		}
+	})
+}
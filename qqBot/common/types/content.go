@@ -0,0 +1,193 @@
+package types
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SegmentType 消息内容片段类型
+type SegmentType int
+
+// 消息内容片段类型
+const (
+	SegmentText            SegmentType = iota // 文本，已反转义
+	SegmentMentionUser                        // @用户，<@!user_id> 或 <@user_id>
+	SegmentMentionEveryone                    // @everyone
+	SegmentChannel                            // #子频道，<#channel_id>
+	SegmentEmoji                              // 系统表情，<emoji:id>
+)
+
+// Segment 消息内容片段
+type Segment struct {
+	Type SegmentType
+	Text string // 文本片段的内容
+	ID   string // 用户、子频道或表情的ID
+}
+
+// 消息内容中的转义字符，文本中的 & < > 会被转义
+var (
+	contentEscaper   = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	contentUnescaper = strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">")
+)
+
+// mentionEveryone @全体成员
+const mentionEveryone = "@everyone"
+
+// Content 解析后的消息内容
+type Content []Segment
+
+// ParseContent 将消息内容解析为片段，无法识别的 <...> 按文本处理
+func ParseContent(content string) Content {
+	var (
+		segments Content
+		text     strings.Builder
+	)
+	flush := func() {
+		if text.Len() > 0 {
+			segments = append(segments, Segment{Type: SegmentText, Text: contentUnescaper.Replace(text.String())})
+			text.Reset()
+		}
+	}
+	for len(content) > 0 {
+		if strings.HasPrefix(content, mentionEveryone) {
+			flush()
+			segments = append(segments, Segment{Type: SegmentMentionEveryone})
+			content = content[len(mentionEveryone):]
+			continue
+		}
+		if content[0] == '<' {
+			if seg, n, ok := parseTag(content); ok {
+				flush()
+				segments = append(segments, seg)
+				content = content[n:]
+				continue
+			}
+		}
+		// 下一个可能的片段之前都是文本
+		next := strings.IndexAny(content[1:], "<@")
+		if next < 0 {
+			text.WriteString(content)
+			break
+		}
+		text.WriteString(content[:next+1])
+		content = content[next+1:]
+	}
+	flush()
+	return segments
+}
+
+// parseTag 解析 content 开头的 <...> 片段，返回片段与其长度
+func parseTag(content string) (Segment, int, bool) {
+	end := strings.IndexByte(content, '>')
+	if end < 0 {
+		return Segment{}, 0, false
+	}
+	tag := content[1:end]
+	var seg Segment
+	switch {
+	case strings.HasPrefix(tag, "@!"):
+		seg = Segment{Type: SegmentMentionUser, ID: tag[2:]}
+	case strings.HasPrefix(tag, "@"):
+		seg = Segment{Type: SegmentMentionUser, ID: tag[1:]}
+	case strings.HasPrefix(tag, "#"):
+		seg = Segment{Type: SegmentChannel, ID: tag[1:]}
+	case strings.HasPrefix(tag, "emoji:"):
+		seg = Segment{Type: SegmentEmoji, ID: tag[len("emoji:"):]}
+	default:
+		return Segment{}, 0, false
+	}
+	if !validSegmentID(seg.ID) {
+		return Segment{}, 0, false
+	}
+	return seg, end + 1, true
+}
+
+// validSegmentID 片段ID不能为空，也不能包含空白字符、< 和 !
+func validSegmentID(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, r := range id {
+		if unicode.IsSpace(r) || r == '<' || r == '!' {
+			return false
+		}
+	}
+	return true
+}
+
+// String 将片段重新编码为消息内容，@用户 统一使用 <@!user_id>
+func (c Content) String() string {
+	var b strings.Builder
+	for _, seg := range c {
+		switch seg.Type {
+		case SegmentText:
+			b.WriteString(contentEscaper.Replace(seg.Text))
+		case SegmentMentionUser:
+			b.WriteString("<@!" + seg.ID + ">")
+		case SegmentMentionEveryone:
+			b.WriteString(mentionEveryone)
+		case SegmentChannel:
+			b.WriteString("<#" + seg.ID + ">")
+		case SegmentEmoji:
+			b.WriteString("<emoji:" + seg.ID + ">")
+		}
+	}
+	return b.String()
+}
+
+// Text 与 String 相同，但文本片段不转义，用于指令解析等需要保留 @成员 的场景
+func (c Content) Text() string {
+	var b strings.Builder
+	for _, seg := range c {
+		if seg.Type == SegmentText {
+			b.WriteString(seg.Text)
+		} else {
+			b.WriteString(Content{seg}.String())
+		}
+	}
+	return b.String()
+}
+
+// PlainText 返回去掉 @、子频道和表情之后的文本，并去除首尾空白
+func (c Content) PlainText() string {
+	var b strings.Builder
+	for _, seg := range c {
+		if seg.Type == SegmentText {
+			b.WriteString(seg.Text)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// Mentions 返回被 @ 的用户ID，按出现顺序排列，可能重复
+func (c Content) Mentions() []string {
+	var ids []string
+	for _, seg := range c {
+		if seg.Type == SegmentMentionUser {
+			ids = append(ids, seg.ID)
+		}
+	}
+	return ids
+}
+
+// MentionsBot 判断消息是否 @ 了机器人
+func (c Content) MentionsBot(botID string) bool {
+	for _, seg := range c {
+		if seg.Type == SegmentMentionUser && seg.ID == botID {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveMention 返回去掉所有 @userID 之后的内容
+func (c Content) RemoveMention(userID string) Content {
+	var segments Content
+	for _, seg := range c {
+		if seg.Type == SegmentMentionUser && seg.ID == userID {
+			continue
+		}
+		segments = append(segments, seg)
+	}
+	return segments
+}
@@ -0,0 +1,94 @@
+package types
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestParseContent(t *testing.T) {
+	t.Run("test segments", func(t *testing.T) {
+		got := ParseContent("<@!bot> 你好 <@u1>&amp;<#c1> @everyone <emoji:4>&lt;b&gt;")
+		want := Content{
+			{Type: SegmentMentionUser, ID: "bot"},
+			{Type: SegmentText, Text: " 你好 "},
+			{Type: SegmentMentionUser, ID: "u1"},
+			{Type: SegmentText, Text: "&"},
+			{Type: SegmentChannel, ID: "c1"},
+			{Type: SegmentText, Text: " "},
+			{Type: SegmentMentionEveryone},
+			{Type: SegmentText, Text: " "},
+			{Type: SegmentEmoji, ID: "4"},
+			{Type: SegmentText, Text: "<b>"},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("unexpected segments %+v", got)
+		}
+		if got.PlainText() != "你好 &  <b>" || !reflect.DeepEqual(got.Mentions(), []string{"bot", "u1"}) {
+			t.Fatalf("unexpected plain text %q or mentions %v", got.PlainText(), got.Mentions())
+		}
+	})
+
+	t.Run("test unknown tags kept as text", func(t *testing.T) {
+		for _, content := range []string{"<@!>", "<@ u1>", "<emoji:>", "<b>", "<@!u1", "a < b", "<@!!u1>", "@every"} {
+			got := ParseContent(content)
+			if len(got) != 1 || got[0].Type != SegmentText || got[0].Text != content {
+				t.Fatalf("parse %q: unexpected segments %+v", content, got)
+			}
+		}
+		if got := ParseContent(""); len(got) != 0 {
+			t.Fatalf("unexpected segments %+v", got)
+		}
+	})
+
+	t.Run("test mentions bot", func(t *testing.T) {
+		// 旧的截取方式在 @ 位于消息末尾时会越界
+		content := ParseContent("/quit <@!bot>")
+		if !content.MentionsBot("bot") || content.MentionsBot("u1") {
+			t.Fatalf("unexpected mentions %v", content.Mentions())
+		}
+		if got := content.RemoveMention("bot"); got.String() != "/quit " || got.PlainText() != "/quit" {
+			t.Fatalf("unexpected content %q", got.String())
+		}
+		content = ParseContent("<@!bot> /echo <@u1> a&amp;b &lt;c&gt;")
+		if got := strings.TrimSpace(content.RemoveMention("bot").Text()); got != "/echo <@!u1> a&b <c>" {
+			t.Fatalf("unexpected content %q", got)
+		}
+	})
+}
+
+func FuzzParseContent(f *testing.F) {
+	for _, seed := range []string{
+		"<@!bot> 成语接龙",
+		"<@u1><#c1><emoji:4>@everyone",
+		"&amp;lt; &lt;@!u1&gt; <",
+		"<@!<@!u1>>",
+		"@everyon@everyone",
+		"",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		content := ParseContent(s)
+		for i, seg := range content {
+			if seg.Type == SegmentText && seg.Text == "" {
+				t.Fatalf("empty text segment in %+v", content)
+			}
+			if i > 0 && seg.Type == SegmentText && content[i-1].Type == SegmentText {
+				t.Fatalf("adjacent text segments in %+v", content)
+			}
+			if seg.Type != SegmentText && seg.Type != SegmentMentionEveryone && !validSegmentID(seg.ID) {
+				t.Fatalf("invalid id in %+v", seg)
+			}
+		}
+		// 重新编码后解析得到相同的片段
+		encoded := content.String()
+		if again := ParseContent(encoded); !reflect.DeepEqual(again, content) {
+			t.Fatalf("round trip of %q through %q: got %+v, want %+v", s, encoded, again, content)
+		}
+		if utf8.ValidString(s) && !utf8.ValidString(content.PlainText()) {
+			t.Fatalf("invalid utf8 plain text from %q", s)
+		}
+	})
+}
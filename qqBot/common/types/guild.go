@@ -0,0 +1,77 @@
+package types
+
+// Guild 频道对象
+type Guild struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Icon        string `json:"icon"`
+	OwnerID     string `json:"owner_id"`
+	IsOwner     bool   `json:"owner"`        // 当前机器人是否是频道的创建者
+	MemberCount int    `json:"member_count"` // 成员数
+	MaxMembers  int64  `json:"max_members"`  // 最大成员数
+	Description string `json:"description"`
+	JoinedAt    string `json:"joined_at"` // 机器人加入频道的时间
+	// 事件中操作者的ID，如邀请机器人加入频道的用户
+	OpUserID string `json:"op_user_id,omitempty"`
+}
+
+// ChannelType 子频道类型
+type ChannelType int
+
+// 子频道类型
+const (
+	ChannelTypeText        ChannelType = 0     // 文字子频道
+	ChannelTypeVoice       ChannelType = 2     // 语音子频道
+	ChannelTypeCategory    ChannelType = 4     // 子频道分组
+	ChannelTypeLive        ChannelType = 10005 // 直播子频道
+	ChannelTypeApplication ChannelType = 10006 // 应用子频道
+	ChannelTypeForum       ChannelType = 10007 // 论坛子频道
+)
+
+// Channel 子频道对象
+type Channel struct {
+	ID              string      `json:"id"`
+	GuildID         string      `json:"guild_id"`
+	Name            string      `json:"name"`
+	Type            ChannelType `json:"type"`
+	SubType         int         `json:"sub_type"` // 子类型，0 闲聊，1 公告，2 攻略，3 开黑
+	Position        int64       `json:"position"` // 排序值
+	ParentID        string      `json:"parent_id"`
+	OwnerID         string      `json:"owner_id"`
+	PrivateType     int         `json:"private_type"`     // 0 公开，1 群主管理员可见，2 群主管理员及指定成员可见
+	SpeakPermission int         `json:"speak_permission"` // 0 无效类型，1 所有人，2 群主管理员及指定成员
+	ApplicationID   string      `json:"application_id"`   // 应用子频道的应用类型ID
+	Permissions     string      `json:"permissions"`      // 用户拥有的子频道权限
+	// 事件中操作者的ID
+	OpUserID string `json:"op_user_id,omitempty"`
+}
+
+// GuildPager 拉取机器人所在频道列表的分页参数，Before 与 After 二选一
+type GuildPager struct {
+	Before string // 读此频道ID之前的数据
+	After  string // 读此频道ID之后的数据
+	Limit  int    // 每页数量，最大 100，为 0 时使用默认值 100
+}
+
+// GuildMembersPager 拉取频道成员列表的分页参数
+type GuildMembersPager struct {
+	After string // 上一次拉取的最后一个成员的用户ID，第一次拉取为空，结果可能包含重复的成员，需要去重
+	Limit int    // 每页数量，最大 400，为 0 时使用默认值 1
+}
+
+// Role 频道身份组
+type Role struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Color       uint32 `json:"color"`        // ARGB 的 HEX 十六进制颜色值转换后的十进制数值
+	Hoist       uint32 `json:"hoist"`        // 是否在成员列表中单独展示，0 否，1 是
+	Number      uint32 `json:"number"`       // 人数
+	MemberLimit uint32 `json:"member_limit"` // 成员上限
+}
+
+// GuildRoles 频道身份组列表
+type GuildRoles struct {
+	GuildID      string  `json:"guild_id"`
+	Roles        []*Role `json:"roles"`
+	RoleNumLimit string  `json:"role_num_limit"` // 默认分组上限
+}
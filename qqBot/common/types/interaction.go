@@ -0,0 +1,61 @@
+package types
+
+// InteractionType 互动事件类型
+type InteractionType int
+
+// InteractionTypeMessageButton 消息按钮回调
+const InteractionTypeMessageButton InteractionType = 11
+
+// InteractionChatType 互动事件发生的场景
+type InteractionChatType int
+
+// 互动事件发生的场景
+const (
+	InteractionChatTypeGuild InteractionChatType = 0 // 频道
+	InteractionChatTypeGroup InteractionChatType = 1 // 群聊
+	InteractionChatTypeC2C   InteractionChatType = 2 // 单聊
+)
+
+// Interaction 互动事件，如点击消息按钮
+type Interaction struct {
+	ID                string              `json:"id"` // 平台方事件ID，回应时使用，也可以作为 event_id 发送被动消息
+	ApplicationID     string              `json:"application_id"`
+	Type              InteractionType     `json:"type"`
+	ChatType          InteractionChatType `json:"chat_type"`
+	Data              *InteractionData    `json:"data"`
+	GuildID           string              `json:"guild_id"`   // 频道场景下的频道ID
+	ChannelID         string              `json:"channel_id"` // 频道场景下的子频道ID
+	GroupOpenID       string              `json:"group_openid"`
+	GroupMemberOpenID string              `json:"group_member_openid"` // 群聊场景下点击按钮的用户
+	UserOpenID        string              `json:"user_openid"`         // 单聊场景下点击按钮的用户
+	Timestamp         string              `json:"timestamp"`
+	Version           uint32              `json:"version"`
+}
+
+// InteractionData 互动事件数据
+type InteractionData struct {
+	Type     InteractionType      `json:"type"`
+	Resolved *InteractionResolved `json:"resolved"`
+}
+
+// InteractionResolved 按钮回调的数据
+type InteractionResolved struct {
+	ButtonID   string `json:"button_id"`   // 按钮ID
+	ButtonData string `json:"button_data"` // 按钮的回调数据
+	UserID     string `json:"user_id"`     // 频道场景下点击按钮的用户
+	MessageID  string `json:"message_id"`  // 按钮所在的消息
+	FeatureID  string `json:"feature_id"`
+}
+
+// InteractionCode 回应互动事件的结果
+type InteractionCode int
+
+// 回应互动事件的结果
+const (
+	InteractionCodeSuccess      InteractionCode = 0 // 成功
+	InteractionCodeFailed       InteractionCode = 1 // 操作失败
+	InteractionCodeTooFrequent  InteractionCode = 2 // 操作频繁
+	InteractionCodeDuplicate    InteractionCode = 3 // 重复操作
+	InteractionCodeNoPermission InteractionCode = 4 // 没有权限
+	InteractionCodeAdminOnly    InteractionCode = 5 // 仅管理员操作
+)
@@ -0,0 +1,101 @@
+package types
+
+// Keyboard 消息按钮，使用模板时填写模板ID，自定义按钮时填写 Content
+type Keyboard struct {
+	ID      string          `json:"id,omitempty"` // 按钮模板ID
+	Content *CustomKeyboard `json:"content,omitempty"`
+}
+
+// CustomKeyboard 自定义按钮，最多 5 行，每行最多 5 个按钮
+type CustomKeyboard struct {
+	Rows []*KeyboardRow `json:"rows"`
+}
+
+// KeyboardRow 一行按钮
+type KeyboardRow struct {
+	Buttons []*Button `json:"buttons"`
+}
+
+// Button 按钮
+type Button struct {
+	ID         string            `json:"id"` // 按钮ID，按钮回调时通过该ID区分按钮
+	RenderData *ButtonRenderData `json:"render_data"`
+	Action     *ButtonAction     `json:"action"`
+}
+
+// ButtonStyle 按钮样式
+type ButtonStyle int
+
+// 按钮样式
+const (
+	ButtonStyleGrey ButtonStyle = 0 // 灰色线框
+	ButtonStyleBlue ButtonStyle = 1 // 蓝色线框
+)
+
+// ButtonRenderData 按钮的显示内容
+type ButtonRenderData struct {
+	Label        string      `json:"label"`         // 按钮上的文字
+	VisitedLabel string      `json:"visited_label"` // 点击后按钮上的文字
+	Style        ButtonStyle `json:"style"`
+}
+
+// ButtonActionType 按钮的操作类型
+type ButtonActionType int
+
+// 按钮的操作类型
+const (
+	ButtonActionLink     ButtonActionType = 0 // 跳转按钮，Data 为链接
+	ButtonActionCallback ButtonActionType = 1 // 回调按钮，点击后产生 INTERACTION_CREATE 事件，Data 为回调数据
+	ButtonActionCommand  ButtonActionType = 2 // 指令按钮，点击后在输入框中填入 Data
+)
+
+// ButtonAction 按钮的操作
+type ButtonAction struct {
+	Type          ButtonActionType  `json:"type"`
+	Permission    *ButtonPermission `json:"permission"`
+	Data          string            `json:"data"`
+	Reply         bool              `json:"reply,omitempty"`          // 指令按钮：是否带引用回复本消息
+	Enter         bool              `json:"enter,omitempty"`          // 指令按钮：是否直接发送 Data
+	UnsupportTips string            `json:"unsupport_tips,omitempty"` // 客户端不支持本按钮时的提示
+}
+
+// ButtonPermissionType 按钮的操作权限类型
+type ButtonPermissionType int
+
+// 按钮的操作权限类型
+const (
+	ButtonPermissionUsers ButtonPermissionType = 0 // 指定用户可操作
+	ButtonPermissionAdmin ButtonPermissionType = 1 // 仅管理者可操作
+	ButtonPermissionAll   ButtonPermissionType = 2 // 所有人可操作
+	ButtonPermissionRoles ButtonPermissionType = 3 // 指定身份组可操作，仅频道可用
+)
+
+// ButtonPermission 按钮的操作权限
+type ButtonPermission struct {
+	Type           ButtonPermissionType `json:"type"`
+	SpecifyUserIDs []string             `json:"specify_user_ids,omitempty"`
+	SpecifyRoleIDs []string             `json:"specify_role_ids,omitempty"`
+}
+
+// NewCallbackButton 创建所有人可操作的回调按钮
+func NewCallbackButton(id string, label string, data string) *Button {
+	return &Button{
+		ID:         id,
+		RenderData: &ButtonRenderData{Label: label, VisitedLabel: label, Style: ButtonStyleBlue},
+		Action: &ButtonAction{
+			Type:          ButtonActionCallback,
+			Permission:    &ButtonPermission{Type: ButtonPermissionAll},
+			Data:          data,
+			UnsupportTips: "当前客户端版本不支持该按钮，请升级后使用",
+		},
+	}
+}
+
+// NewKeyboard 使用自定义按钮创建 Keyboard，每个参数为一行
+func NewKeyboard(rows ...[]*Button) *Keyboard {
+	content := &CustomKeyboard{}
+	for _, buttons := range rows {
+		content.Rows = append(content.Rows, &KeyboardRow{Buttons: buttons})
+	}
+	return &Keyboard{Content: content}
+}
@@ -0,0 +1,33 @@
+package types
+
+// FileType 富媒体文件类型
+type FileType int
+
+// 富媒体文件类型
+const (
+	FileTypeImage FileType = 1 // 图片，png/jpg
+	FileTypeVideo FileType = 2 // 视频，mp4
+	FileTypeVoice FileType = 3 // 语音，silk
+	FileTypeFile  FileType = 4 // 文件，暂不开放
+)
+
+// MediaToUpload 群聊和单聊上传富媒体文件的请求，URL 与 FileData 二选一
+type MediaToUpload struct {
+	FileType   FileType `json:"file_type"`
+	URL        string   `json:"url,omitempty"`
+	FileData   string   `json:"file_data,omitempty"` // base64 编码的文件内容
+	SrvSendMsg bool     `json:"srv_send_msg"`        // 为 true 时上传后直接发送消息，会占用主动消息频次
+}
+
+// Media 上传富媒体文件的返回
+type Media struct {
+	FileUUID string `json:"file_uuid"`
+	FileInfo string `json:"file_info"` // 发送富媒体消息时使用
+	TTL      int    `json:"ttl"`       // 有效期，单位秒，0 表示长期有效
+	ID       string `json:"id"`        // SrvSendMsg 为 true 时返回的消息ID
+}
+
+// MessageMedia 富媒体消息中的文件
+type MessageMedia struct {
+	FileInfo string `json:"file_info"`
+}
@@ -23,6 +23,7 @@ type Message struct {
 	// 附件
 	Attachments []*MessageAttachment `json:"attachments"`
 	// 结构化消息-embeds
+	Embeds []*Embed `json:"embeds"`
 	// 消息中的提醒信息(@)列表
 	Mentions []*User `json:"mentions"`
 	// ark 消息
@@ -32,8 +33,22 @@ type Message struct {
 	// 子频道 seq，用于消息间的排序，seq 在同一子频道中按从先到后的顺序递增，不同的子频道之前消息无法排序
 	SeqInChannel string `json:"seq_in_channel"`
 	// 引用的消息
+	MessageReference *MessageReference `json:"message_reference"`
 	// 私信场景下，该字段用来标识从哪个频道发起的私信
 	SrcGuildID string `json:"src_guild_id"`
+	// 群聊ID，群聊消息中使用
+	GroupID string `json:"group_id"`
+	// 群聊的 openid，群聊消息中使用
+	GroupOpenID string `json:"group_openid"`
+}
+
+// Embed embed 消息
+type Embed struct {
+	Title       string                `json:"title,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Prompt      string                `json:"prompt,omitempty"` // 消息弹窗内容
+	Thumbnail   MessageEmbedThumbnail `json:"thumbnail,omitempty"`
+	Fields      []*EmbedField         `json:"fields,omitempty"`
 }
 
 // MessageEmbedThumbnail embed 消息的缩略图对象
@@ -47,6 +62,25 @@ type EmbedField struct {
 	Value string `json:"value,omitempty"`
 }
 
+// MessageReference 引用消息对象
+type MessageReference struct {
+	MessageID             string `json:"message_id"`                         // 被引用的消息ID
+	IgnoreGetMessageError bool   `json:"ignore_get_message_error,omitempty"` // 是否忽略获取引用消息详情错误
+}
+
+// Markdown markdown 消息，使用模板时填写模板ID和参数，使用原生 markdown 时填写 Content
+type Markdown struct {
+	CustomTemplateID string           `json:"custom_template_id,omitempty"` // 模板ID
+	Params           []*MarkdownParam `json:"params,omitempty"`             // 模板参数
+	Content          string           `json:"content,omitempty"`            // 原生 markdown 内容
+}
+
+// MarkdownParam markdown 模板参数
+type MarkdownParam struct {
+	Key    string   `json:"key"`
+	Values []string `json:"values"`
+}
+
 // MessageAttachment 附件定义
 type MessageAttachment struct {
 	URL string `json:"url"`
@@ -60,6 +94,8 @@ type User struct {
 	Bot              bool   `json:"bot"`
 	UnionOpenID      string `json:"union_openid"`       // 特殊关联应用的 openid
 	UnionUserAccount string `json:"union_user_account"` // 机器人关联的用户信息，与union_openid关联的应用是同一个
+	MemberOpenID     string `json:"member_openid"`      // 群聊消息中发送者在群内的 openid
+	UserOpenID       string `json:"user_openid"`        // 单聊消息中发送者的 openid
 }
 
 // Member 群成员
@@ -100,7 +136,42 @@ type ArkObjKV struct {
 type MessageToCreate struct {
 	Content string `json:"content,omitempty"`
 	Ark     *Ark   `json:"ark,omitempty"`
+	Embed   *Embed `json:"embed,omitempty"`
+	Image   string `json:"image,omitempty"` // 图片 url 地址
+	// markdown 消息，需要机器人开通 markdown 权限
+	Markdown *Markdown `json:"markdown,omitempty"`
+	// 消息按钮，只能与 markdown 一起发送
+	Keyboard *Keyboard `json:"keyboard,omitempty"`
+	// 引用消息，不支持 ark、embed
+	MessageReference *MessageReference `json:"message_reference,omitempty"`
+	// 富媒体消息，仅用于群聊和单聊(v2)接口，FileInfo 为上传文件后返回的 file_info
+	Media *MessageMedia `json:"media,omitempty"`
 	// 要回复的消息id，为空是主动消息，公域机器人会异步审核，不为空是被动消息，公域机器人会校验语料
 	MsgID   string `json:"msg_id,omitempty"`
 	EventID string `json:"event_id,omitempty"` // 要回复的事件id, 逻辑同MsgID
+	// 以下字段仅用于群聊和单聊(v2)接口，子频道和私信接口发送时会去掉
+	MsgType MsgType `json:"msg_type,omitempty"` // 消息类型：0 文本，2 markdown，3 ark，4 embed，7 富媒体
+	MsgSeq  uint32  `json:"msg_seq,omitempty"`  // 回复消息的序号，同一条消息的多次回复需要不同的序号，为 0 时自动递增
+}
+
+// MessageResponse 群聊和单聊(v2)发送消息接口的返回
+type MessageResponse struct {
+	ID        string      `json:"id"`
+	Timestamp interface{} `json:"timestamp"` // 不同接口返回的可能是字符串也可能是数字
+}
+
+// DirectMessage 私信会话
+type DirectMessage struct {
+	// 私信会话的频道ID
+	GuildID string `json:"guild_id"`
+	// 私信会话的子频道ID
+	ChannelID string `json:"channel_id"`
+	// 创建私信会话的时间戳
+	CreateTime string `json:"create_time"`
+}
+
+// DirectMessageToCreate 创建私信会话的结构体定义
+type DirectMessageToCreate struct {
+	SourceGuildID string `json:"source_guild_id"` // 用户与机器人共同所在的频道ID
+	RecipientID   string `json:"recipient_id"`    // 接收私信的用户ID
 }
@@ -0,0 +1,201 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MsgType 群聊和单聊(v2)接口的消息类型
+type MsgType int
+
+// 群聊和单聊(v2)接口的消息类型
+const (
+	MsgTypeText     MsgType = 0
+	MsgTypeMarkdown MsgType = 2
+	MsgTypeArk      MsgType = 3
+	MsgTypeEmbed    MsgType = 4
+	MsgTypeMedia    MsgType = 7 // 富媒体
+)
+
+// 自定义按钮的数量限制
+const (
+	maxKeyboardRows   = 5
+	maxButtonsPerRow  = 5
+	maxButtonIDLength = 64
+)
+
+// ErrInvalidMessage 消息内容的组合不被平台支持
+var ErrInvalidMessage = errors.New("invalid message")
+
+// MessageBuilder 链式构造 MessageToCreate，Build 时校验字段组合是否合法
+type MessageBuilder struct {
+	msg MessageToCreate
+}
+
+// NewMessageBuilder 创建消息构造器
+func NewMessageBuilder() *MessageBuilder {
+	return &MessageBuilder{}
+}
+
+// Content 文本内容
+func (b *MessageBuilder) Content(content string) *MessageBuilder {
+	b.msg.Content = content
+	return b
+}
+
+// Image 图片 url 地址
+func (b *MessageBuilder) Image(url string) *MessageBuilder {
+	b.msg.Image = url
+	return b
+}
+
+// Embed embed 消息
+func (b *MessageBuilder) Embed(embed *Embed) *MessageBuilder {
+	b.msg.Embed = embed
+	return b
+}
+
+// Ark ark 模板消息
+func (b *MessageBuilder) Ark(ark *Ark) *MessageBuilder {
+	b.msg.Ark = ark
+	return b
+}
+
+// Markdown 原生 markdown 消息
+func (b *MessageBuilder) Markdown(content string) *MessageBuilder {
+	b.msg.Markdown = &Markdown{Content: content}
+	return b
+}
+
+// MarkdownTemplate 模板 markdown 消息
+func (b *MessageBuilder) MarkdownTemplate(templateID string, params ...*MarkdownParam) *MessageBuilder {
+	b.msg.Markdown = &Markdown{CustomTemplateID: templateID, Params: params}
+	return b
+}
+
+// Keyboard 消息按钮，需要与 markdown 一起发送
+func (b *MessageBuilder) Keyboard(keyboard *Keyboard) *MessageBuilder {
+	b.msg.Keyboard = keyboard
+	return b
+}
+
+// Media 富媒体消息，仅用于群聊和单聊接口，fileInfo 为上传文件后返回的 file_info
+func (b *MessageBuilder) Media(fileInfo string) *MessageBuilder {
+	b.msg.Media = &MessageMedia{FileInfo: fileInfo}
+	return b
+}
+
+// Reply 被动回复消息
+func (b *MessageBuilder) Reply(msgID string) *MessageBuilder {
+	b.msg.MsgID = msgID
+	return b
+}
+
+// ReplyEvent 被动回复事件
+func (b *MessageBuilder) ReplyEvent(eventID string) *MessageBuilder {
+	b.msg.EventID = eventID
+	return b
+}
+
+// Quote 引用消息
+func (b *MessageBuilder) Quote(msgID string) *MessageBuilder {
+	b.msg.MessageReference = &MessageReference{MessageID: msgID, IgnoreGetMessageError: true}
+	return b
+}
+
+// Build 校验并返回消息，同时根据内容设置群聊和单聊接口使用的 MsgType
+func (b *MessageBuilder) Build() (*MessageToCreate, error) {
+	msg := b.msg
+	if err := ValidateMessage(&msg); err != nil {
+		return nil, err
+	}
+	switch {
+	case msg.Media != nil:
+		msg.MsgType = MsgTypeMedia
+	case msg.Markdown != nil:
+		msg.MsgType = MsgTypeMarkdown
+	case msg.Ark != nil:
+		msg.MsgType = MsgTypeArk
+	case msg.Embed != nil:
+		msg.MsgType = MsgTypeEmbed
+	default:
+		msg.MsgType = MsgTypeText
+	}
+	return &msg, nil
+}
+
+// ValidateMessage 校验消息字段的组合是否被平台支持
+func ValidateMessage(msg *MessageToCreate) error {
+	if msg.Content == "" && msg.Image == "" && msg.Embed == nil && msg.Ark == nil && msg.Markdown == nil && msg.Media == nil {
+		return fmt.Errorf("%w: content, image, embed, ark, markdown or media is required", ErrInvalidMessage)
+	}
+	var structured int
+	for _, set := range []bool{msg.Embed != nil, msg.Ark != nil, msg.Markdown != nil, msg.Media != nil} {
+		if set {
+			structured++
+		}
+	}
+	if structured > 1 {
+		return fmt.Errorf("%w: embed, ark, markdown and media can not be sent together", ErrInvalidMessage)
+	}
+	if msg.Media != nil && msg.Media.FileInfo == "" {
+		return fmt.Errorf("%w: media file info is required", ErrInvalidMessage)
+	}
+	if msg.MessageReference != nil && (msg.Embed != nil || msg.Ark != nil) {
+		return fmt.Errorf("%w: message reference does not support embed or ark", ErrInvalidMessage)
+	}
+	if md := msg.Markdown; md != nil {
+		if md.Content != "" && md.CustomTemplateID != "" {
+			return fmt.Errorf("%w: markdown template and content can not be set together", ErrInvalidMessage)
+		}
+		if md.Content == "" && md.CustomTemplateID == "" {
+			return fmt.Errorf("%w: markdown template or content is required", ErrInvalidMessage)
+		}
+		if md.Content != "" && len(md.Params) > 0 {
+			return fmt.Errorf("%w: markdown params require a template", ErrInvalidMessage)
+		}
+	}
+	if msg.Keyboard != nil {
+		if msg.Markdown == nil {
+			return fmt.Errorf("%w: keyboard must be sent with markdown", ErrInvalidMessage)
+		}
+		if err := validateKeyboard(msg.Keyboard); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateKeyboard 校验按钮模板ID与自定义按钮二选一，以及自定义按钮的数量和内容
+func validateKeyboard(keyboard *Keyboard) error {
+	if (keyboard.ID == "") == (keyboard.Content == nil) {
+		return fmt.Errorf("%w: keyboard requires either a template id or custom content", ErrInvalidMessage)
+	}
+	if keyboard.Content == nil {
+		return nil
+	}
+	rows := keyboard.Content.Rows
+	if len(rows) == 0 || len(rows) > maxKeyboardRows {
+		return fmt.Errorf("%w: keyboard requires 1 to %d rows", ErrInvalidMessage, maxKeyboardRows)
+	}
+	ids := make(map[string]bool)
+	for i, row := range rows {
+		if row == nil || len(row.Buttons) == 0 || len(row.Buttons) > maxButtonsPerRow {
+			return fmt.Errorf("%w: keyboard row %d requires 1 to %d buttons", ErrInvalidMessage, i, maxButtonsPerRow)
+		}
+		for _, button := range row.Buttons {
+			switch {
+			case button == nil || button.RenderData == nil || button.Action == nil:
+				return fmt.Errorf("%w: button in row %d requires render data and action", ErrInvalidMessage, i)
+			case button.ID == "" || len(button.ID) > maxButtonIDLength:
+				return fmt.Errorf("%w: button id %q is empty or too long", ErrInvalidMessage, button.ID)
+			case ids[button.ID]:
+				return fmt.Errorf("%w: duplicate button id %q", ErrInvalidMessage, button.ID)
+			case button.Action.Permission == nil:
+				return fmt.Errorf("%w: button %q requires permission", ErrInvalidMessage, button.ID)
+			}
+			ids[button.ID] = true
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package types
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMessageBuilder(t *testing.T) {
+	keyboard := NewKeyboard([]*Button{NewCallbackButton("hint", "提示", "hint"), NewCallbackButton("quit", "认输", "quit")})
+
+	t.Run("test msg type set by content", func(t *testing.T) {
+		cases := []struct {
+			builder *MessageBuilder
+			want    MsgType
+		}{
+			{NewMessageBuilder().Content("hi").Quote("m1"), MsgTypeText},
+			{NewMessageBuilder().Markdown("**hi**").Keyboard(keyboard), MsgTypeMarkdown},
+			{NewMessageBuilder().Ark(&Ark{TemplateID: 23}), MsgTypeArk},
+			{NewMessageBuilder().Embed(&Embed{Title: "t"}).Content("hi"), MsgTypeEmbed},
+			{NewMessageBuilder().Media("info"), MsgTypeMedia},
+		}
+		for i, c := range cases {
+			msg, err := c.builder.Build()
+			if err != nil || msg.MsgType != c.want {
+				t.Fatalf("case %d: unexpected message %+v, err %v", i, msg, err)
+			}
+		}
+	})
+
+	t.Run("test invalid combinations rejected", func(t *testing.T) {
+		cases := []*MessageBuilder{
+			NewMessageBuilder(),
+			NewMessageBuilder().Content("hi").Keyboard(keyboard),
+			NewMessageBuilder().Markdown("hi").Ark(&Ark{}),
+			NewMessageBuilder().Embed(&Embed{}).Quote("m1"),
+			NewMessageBuilder().Markdown("hi").Keyboard(&Keyboard{}),
+			NewMessageBuilder().Markdown("hi").Keyboard(NewKeyboard([]*Button{
+				NewCallbackButton("a", "a", "a"), NewCallbackButton("a", "b", "b"),
+			})),
+			NewMessageBuilder().MarkdownTemplate(""),
+			NewMessageBuilder().Media("info").Markdown("hi"),
+		}
+		for i, b := range cases {
+			if _, err := b.Build(); !errors.Is(err, ErrInvalidMessage) {
+				t.Fatalf("case %d: expect invalid message, got %v", i, err)
+			}
+		}
+	})
+}
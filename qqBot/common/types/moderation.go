@@ -0,0 +1,50 @@
+package types
+
+import (
+	"strconv"
+	"time"
+)
+
+// 系统默认身份组ID
+const (
+	RoleIDAll          = "1" // 全体成员
+	RoleIDAdmin        = "2" // 管理员
+	RoleIDOwner        = "4" // 频道主
+	RoleIDChannelAdmin = "5" // 子频道管理员
+)
+
+// UpdateGuildMute 禁言参数，MuteEndTimestamp 与 MuteSeconds 二选一，同时指定时 MuteEndTimestamp 优先，
+// MuteSeconds 为 "0" 表示解除禁言
+type UpdateGuildMute struct {
+	MuteEndTimestamp string   `json:"mute_end_timestamp,omitempty"` // 禁言到期时间戳，单位秒
+	MuteSeconds      string   `json:"mute_seconds,omitempty"`       // 禁言时长，单位秒
+	UserIDs          []string `json:"user_ids,omitempty"`           // 批量禁言的成员
+}
+
+// MuteFor 禁言一段时间，d 为 0 时解除禁言
+func MuteFor(d time.Duration) *UpdateGuildMute {
+	return &UpdateGuildMute{MuteSeconds: strconv.FormatInt(int64(d/time.Second), 10)}
+}
+
+// MuteUntil 禁言到指定时间
+func MuteUntil(t time.Time) *UpdateGuildMute {
+	return &UpdateGuildMute{MuteEndTimestamp: strconv.FormatInt(t.Unix(), 10)}
+}
+
+// MuteMembersResult 批量禁言的结果
+type MuteMembersResult struct {
+	UserIDs []string `json:"user_ids"` // 设置成功的成员
+}
+
+// MemberDeleteOpts 移除成员的参数
+type MemberDeleteOpts struct {
+	AddBlacklist         bool `json:"add_blacklist"`           // 同时添加到黑名单
+	DeleteHistoryMsgDays int  `json:"delete_history_msg_days"` // 撤回成员的消息，3/7/15/30 表示最近的天数，-1 表示全部，0 表示不撤回
+}
+
+// RoleChannel 子频道管理员身份组需要指定的子频道
+type RoleChannel struct {
+	Channel struct {
+		ID string `json:"id"`
+	} `json:"channel"`
+}
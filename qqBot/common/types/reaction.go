@@ -0,0 +1,55 @@
+package types
+
+// EmojiType 表情类型
+type EmojiType int
+
+// 表情类型
+const (
+	EmojiTypeSystem EmojiType = 1 // 系统表情
+	EmojiTypeEmoji  EmojiType = 2 // emoji 表情
+)
+
+// Emoji 表情对象，系统表情的ID为数字，emoji 表情的ID为 emoji 本身
+type Emoji struct {
+	ID   string    `json:"id"`
+	Type EmojiType `json:"type"`
+}
+
+// ReactionTargetType 表情表态的对象类型
+type ReactionTargetType int
+
+// 表情表态的对象类型
+const (
+	ReactionTargetTypeMessage ReactionTargetType = 0 // 消息
+	ReactionTargetTypeFeed    ReactionTargetType = 1 // 帖子
+	ReactionTargetTypeComment ReactionTargetType = 2 // 评论
+	ReactionTargetTypeReply   ReactionTargetType = 3 // 回复
+)
+
+// ReactionTarget 表情表态的对象
+type ReactionTarget struct {
+	ID   string             `json:"id"`
+	Type ReactionTargetType `json:"type"`
+}
+
+// MessageReaction 表情表态事件
+type MessageReaction struct {
+	UserID    string         `json:"user_id"`
+	GuildID   string         `json:"guild_id"`
+	ChannelID string         `json:"channel_id"`
+	Target    ReactionTarget `json:"target"`
+	Emoji     Emoji          `json:"emoji"`
+}
+
+// ReactionUsersPager 拉取表情表态用户列表的分页参数
+type ReactionUsersPager struct {
+	Cookie string // 上一次请求返回的 cookie，第一次请求为空
+	Limit  int    // 每页数量，最大 50，为 0 时使用默认值 20
+}
+
+// MessageReactionUsers 表情表态用户列表
+type MessageReactionUsers struct {
+	Users  []*User `json:"users"`
+	Cookie string  `json:"cookie"` // 分页参数，用于拉取下一页
+	IsEnd  bool    `json:"is_end"` // 是否已拉取完成
+}
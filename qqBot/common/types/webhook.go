@@ -0,0 +1,18 @@
+package types
+
+// WHValidationReq 回调地址验证请求(op=13)的数据
+type WHValidationReq struct {
+	PlainToken string `json:"plain_token"`
+	EventTs    string `json:"event_ts"`
+}
+
+// WHValidationRsp 回调地址验证的响应，signature 为对 event_ts+plain_token 的 ed25519 签名
+type WHValidationRsp struct {
+	PlainToken string `json:"plain_token"`
+	Signature  string `json:"signature"`
+}
+
+// WHCallbackAck 回调事件的确认响应(op=12)
+type WHCallbackAck struct {
+	OPCode int `json:"op"`
+}
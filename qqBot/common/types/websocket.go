@@ -15,6 +15,7 @@ type WSPayloadBase struct {
 	OPCode int    `json:"op"`
 	Seq    uint32 `json:"s,omitempty"`
 	Type   string `json:"t,omitempty"`
+	ID     string `json:"id,omitempty"` // 事件ID，可作为 event_id 发送被动消息
 }
 
 // ShardConfig 连接的 shard 配置，ShardID 从 0 开始，ShardCount 最小为 1
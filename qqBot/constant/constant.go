@@ -1,10 +1,50 @@
 package constant
 
 const (
-	GatewayURL             = "https://api.sgroup.qq.com/gateway/bot"
-	ChannelsURL            = "https://api.sgroup.qq.com/channels/{channel_id}/messages"
-	DashScopeAPIURL string = "https://dashscope.aliyuncs.com/compatible-mode/v1/chat/completions"
-	DashScopeModel  string = "qwen-turbo"
+	AppAccessTokenURL        = "https://bots.qq.com/app/getAppAccessToken"
+	DashScopeAPIURL   string = "https://dashscope.aliyuncs.com/compatible-mode/v1/chat/completions"
+	DashScopeModel    string = "qwen-turbo"
+)
+
+// OpenAPI 域名
+const (
+	ProductionDomain = "https://api.sgroup.qq.com"
+	SandboxDomain    = "https://sandbox.api.sgroup.qq.com"
+)
+
+// OpenAPI 接口路径，相对于 HttpClient 的 OpenAPI 域名
+const (
+	GatewayURI  = "/gateway/bot"
+	MessagesURI = "/channels/{channel_id}/messages"
+	UserMeDMURI = "/users/@me/dms"
+	DMsURI      = "/dms/{guild_id}/messages"
+
+	UserMeURI       = "/users/@me"
+	UserMeGuildsURI = "/users/@me/guilds"
+	GuildURI        = "/guilds/{guild_id}"
+	ChannelsURI     = "/guilds/{guild_id}/channels"
+	ChannelURI      = "/channels/{channel_id}"
+	GuildMembersURI = "/guilds/{guild_id}/members"
+	RolesURI        = "/guilds/{guild_id}/roles"
+
+	GuildMuteURI   = "/guilds/{guild_id}/mute"
+	MemberMuteURI  = "/guilds/{guild_id}/members/{user_id}/mute"
+	GuildMemberURI = "/guilds/{guild_id}/members/{user_id}"
+	MemberRoleURI  = "/guilds/{guild_id}/members/{user_id}/roles/{role_id}"
+
+	MessageURI   = "/channels/{channel_id}/messages/{message_id}"
+	DMMessageURI = "/dms/{guild_id}/messages/{message_id}"
+
+	MessageReactionURI = "/channels/{channel_id}/messages/{message_id}/reactions/{emoji_type}/{emoji_id}"
+
+	InteractionURI = "/interactions/{interaction_id}"
+
+	GroupMessagesURI = "/v2/groups/{group_openid}/messages"
+	C2CMessagesURI   = "/v2/users/{openid}/messages"
+	GroupMessageURI  = "/v2/groups/{group_openid}/messages/{message_id}"
+	C2CMessageURI    = "/v2/users/{openid}/messages/{message_id}"
+	GroupFilesURI    = "/v2/groups/{group_openid}/files"
+	C2CFilesURI      = "/v2/users/{openid}/files"
 )
 
 // WS OPCode
@@ -22,4 +62,16 @@ const (
 	WSHello
 	WSHeartbeatAck
 	HTTPCallbackAck
+	HTTPCallbackValidation
+)
+
+// Intents 事件订阅对应的 intent 位
+const (
+	IntentGuilds                = 1 << 0  // GUILD_CREATE、GUILD_UPDATE、GUILD_DELETE、CHANNEL_CREATE、CHANNEL_UPDATE、CHANNEL_DELETE
+	IntentGuildMembers          = 1 << 1  // GUILD_MEMBER_ADD、GUILD_MEMBER_UPDATE、GUILD_MEMBER_REMOVE
+	IntentGuildMessageReactions = 1 << 10 // MESSAGE_REACTION_ADD、MESSAGE_REACTION_REMOVE
+	IntentDirectMessages        = 1 << 12 // DIRECT_MESSAGE_CREATE，频道私信消息
+	IntentGroupAndC2C           = 1 << 25 // GROUP_AT_MESSAGE_CREATE、C2C_MESSAGE_CREATE，群聊 @机器人 消息和单聊消息
+	IntentInteraction           = 1 << 26 // INTERACTION_CREATE，互动事件，如消息按钮回调
+	IntentGuildAtMessages       = 1 << 30 // AT_MESSAGE_CREATE，公域机器人的 @机器人 消息
 )
@@ -5,22 +5,38 @@ import (
 	"log"
 	"os"
 	"qqbot/common/clients"
+	"qqbot/common/model"
 	"qqbot/common/service"
 	"qqbot/common/types"
+	"qqbot/constant"
 	"qqbot/server"
 	"qqbot/utils"
-	"strings"
 	"time"
 )
 
+// gameTimeout 成语接龙无人回答时自动结束游戏的时间
+const gameTimeout = 60 * time.Second
+
+// 成语接龙游戏的按钮ID
+const (
+	buttonHint      = "idiom_hint"
+	buttonSurrender = "idiom_surrender"
+)
+
+// gameKeyboard 成语接龙游戏进行中时附带的按钮
+var gameKeyboard = types.NewKeyboard([]*types.Button{
+	types.NewCallbackButton(buttonHint, "提示", buttonHint),
+	types.NewCallbackButton(buttonSurrender, "认输", buttonSurrender),
+})
+
 var (
-	ctx          context.Context
-	httpClient   *service.HttpClient
-	ws           *types.WebsocketAP
-	replyMessage string
-	finishOrNot  bool
-	err          error
-	timer        *time.Timer
+	ctx        context.Context
+	httpClient *service.HttpClient
+	ws         *types.WebsocketAP
+	games      *server.GameManager
+	buttons    *service.ButtonRouter
+	commands   *server.Router
+	err        error
 )
 
 func init() {
@@ -28,15 +44,86 @@ func init() {
 	utils.NewConfig()
 	// 初始化数据库链接
 	clients.NewDBClient(utils.ConfigInfo)
+	if err = model.AutoMigrate(clients.GlobalConn); err != nil {
+		log.Fatalln("migrate err:", err)
+	}
 	// 初始化成语库
 	server.NewIdiomMap()
+	// 初始化游戏会话管理器
+	games = server.NewGameManager(gameTimeout, utils.ConfigInfo.GamePerUser)
 	// 初始化http连接
-	httpClient = service.NewClient(utils.ConfigInfo.AppID, utils.ConfigInfo.Token, 3*time.Second)
+	httpClient = service.NewClient(utils.ConfigInfo.AppID, utils.ConfigInfo.Token, 3*time.Second,
+		service.WithTokenSource(newTokenSource()), openAPIOption())
+	// 允许事件处理过程中撤回收到的消息
+	service.EnableMessageDeletion(httpClient)
+	// 初始化指令，执行前检查权限，频道授权保存在数据库中
+	commands = server.NewRouter(server.NewPermissionChecker(&model.CommandGrantStore{DB: clients.GlobalConn}))
+	for _, cmd := range append(server.NewGameCommands(games), server.NewMuteCommand(httpClient)) {
+		if err = commands.Register(cmd); err != nil {
+			log.Fatalln("register command err:", err)
+		}
+	}
+	// 初始化按钮回调
+	buttons = service.NewButtonRouter(httpClient)
+	buttons.Handle(buttonHint, HintButtonCallback)
+	buttons.Handle(buttonSurrender, SurrenderButtonCallback)
+}
+
+// openAPIOption 根据配置选择 OpenAPI 环境，自定义地址优先于沙箱环境
+func openAPIOption() service.ClientOption {
+	if utils.ConfigInfo.OpenAPIURL != "" {
+		return service.WithBaseURL(utils.ConfigInfo.OpenAPIURL)
+	}
+	if utils.ConfigInfo.Sandbox {
+		return service.WithSandbox()
+	}
+	return service.WithBaseURL(constant.ProductionDomain)
+}
+
+// newTokenSource 配置了机器人密钥时使用 AppID 和密钥换取 QQBot access token，否则使用旧版静态 token
+func newTokenSource() service.TokenSource {
+	if utils.ConfigInfo.Secret != "" {
+		return service.NewAppAccessTokenSource(utils.ConfigInfo.AppID, utils.ConfigInfo.Secret, "")
+	}
+	return &service.Token{
+		AppID:       utils.ConfigInfo.AppID,
+		AccessToken: utils.ConfigInfo.Token,
+		Type:        service.TokenTypeBot,
+	}
 }
 
 func main() {
 	// 获取context
 	ctx = context.Background()
+
+	// 注册@消息、私信、群聊和单聊消息的回调函数
+	var atMessage service.ATMessageEventHandler = AtMessageEventHandler
+	var directMessage service.DirectMessageEventHandler = DirectMessageEventHandler
+	var groupATMessage service.GroupATMessageEventHandler = GroupATMessageEventHandler
+	var c2cMessage service.C2CMessageEventHandler = C2CMessageEventHandler
+	// 注册频道和子频道事件的回调函数，机器人退出频道或子频道被删除时清理游戏会话
+	var guildEvent service.GuildEventHandler = GuildEventHandler
+	var channelEvent service.ChannelEventHandler = ChannelEventHandler
+	// 注册频道成员事件的回调函数，成员加入、退出时发送欢迎、告别消息
+	var guildMemberEvent service.GuildMemberEventHandler = GuildMemberEventHandler
+	// 注册按钮回调事件
+	var interaction service.InteractionEventHandler = buttons.HandleInteraction
+	// 记录所有事件的处理耗时和错误，消息和成员事件跳过机器人
+	service.DefaultEventBus.Use(service.Logging())
+	ignoreBots := service.IgnoreBots()
+	intent := service.RegisterHandlers(
+		service.WithMiddleware(atMessage, ignoreBots), service.WithMiddleware(directMessage, ignoreBots),
+		service.WithMiddleware(groupATMessage, ignoreBots), service.WithMiddleware(c2cMessage, ignoreBots),
+		guildEvent, channelEvent, service.WithMiddleware(guildMemberEvent, ignoreBots), interaction)
+
+	// 配置了回调地址时以 HTTP 回调模式接收事件，无需建立 websocket 长连接
+	if utils.ConfigInfo.WebhookAddr != "" {
+		if err = service.ListenWebhook(utils.ConfigInfo.WebhookAddr, utils.ConfigInfo.Secret); err != nil {
+			log.Fatalln("webhook err:", err)
+		}
+		return
+	}
+
 	// 通过http获取webSocket连接地址
 	ws, err = httpClient.GetWSS(ctx)
 	if err != nil {
@@ -45,15 +132,7 @@ func main() {
 	}
 	log.Printf("%+v, err:%v", ws, err)
 
-	// 注册@消息的回调函数
-	var atMessage service.ATMessageEventHandler = AtMessageEventHandler
-	intent := service.RegisterHandlers(atMessage)
-	err = service.NewSessionManager().Start(ws,
-		&service.Token{
-			AppID:       utils.ConfigInfo.AppID,
-			AccessToken: utils.ConfigInfo.Token,
-			Type:        "Bot",
-		}, intent)
+	err = service.NewSessionManager().Start(ws, httpClient.TokenSource(), intent)
 	if err != nil {
 		log.Printf("Failed to start session manager for appID: %d with error: %v", utils.ConfigInfo.AppID, err)
 	}
@@ -61,82 +140,227 @@ func main() {
 
 // AtMessageEventHandler 处理 @机器人消息的回调函数
 func AtMessageEventHandler(event *types.WSPayload, data *types.Message) error {
-	messageContent := data.Content[strings.Index(data.Content, ">")+2:]
-	// FinishOrNot游戏是否还在进行标记位
-	if finishOrNot {
-		// 重置定时器
-		resetTimer(data)
-		replyMessage = GameInProgress(messageContent, data)
+	content := types.ParseContent(data.Content)
+	botID := ""
+	if me, ok := service.DefaultState.Me(); ok {
+		botID = me.ID
+	} else if len(content) > 0 && content[0].Type == types.SegmentMentionUser {
+		// HTTP 回调模式没有 READY 事件，@机器人 位于消息开头
+		botID = content[0].ID
+	}
+	// 去掉 @机器人，保留 @其他成员，指令参数中会用到
+	if content.MentionsBot(botID) {
+		content = content.RemoveMention(botID)
+	}
+	handleMessage(content, data, channelReplier(data.ChannelID))
+	return nil
+}
+
+// DirectMessageEventHandler 处理私信消息的回调函数，私信中同样可以进行游戏和对话
+func DirectMessageEventHandler(event *types.WSPayload, data *types.Message) error {
+	handleMessage(types.ParseContent(data.Content), data, directReplier(data.GuildID))
+	return nil
+}
+
+// GroupATMessageEventHandler 处理群聊 @机器人 消息的回调函数，群聊消息中不包含 @机器人 的内容
+func GroupATMessageEventHandler(event *types.WSPayload, data *types.Message) error {
+	handleMessage(types.ParseContent(data.Content), data, groupReplier(data.GroupOpenID))
+	return nil
+}
+
+// C2CMessageEventHandler 处理单聊消息的回调函数
+func C2CMessageEventHandler(event *types.WSPayload, data *types.Message) error {
+	handleMessage(types.ParseContent(data.Content), data, c2cReplier(authorID(data)))
+	return nil
+}
+
+// GuildEventHandler 处理机器人加入、退出频道以及频道信息变更的回调函数
+func GuildEventHandler(event *types.WSPayload, data *types.Guild) error {
+	switch event.Type {
+	case service.EventGuildCreate:
+		log.Printf("joined guild %s(%s)", data.Name, data.ID)
+	case service.EventGuildDelete:
+		n := games.FinishIn(data.ID, "")
+		log.Printf("left guild %s(%s), %d games finished", data.Name, data.ID, n)
+	}
+	return nil
+}
+
+// ChannelEventHandler 处理子频道变更的回调函数
+func ChannelEventHandler(event *types.WSPayload, data *types.Channel) error {
+	if event.Type == service.EventChannelDelete {
+		games.FinishIn(data.GuildID, data.ID)
+	}
+	return nil
+}
+
+// GuildMemberEventHandler 成员加入、退出频道时，按频道配置的模板发送欢迎、告别消息
+func GuildMemberEventHandler(event *types.WSPayload, data *types.Member) error {
+	// 成员信息变更不需要发送消息，不查询配置
+	if event.Type != service.EventGuildMemberAdd && event.Type != service.EventGuildMemberRemove {
+		return nil
+	}
+	config, err := model.GetWelcomeConfig(clients.GlobalConn, data.GuildID)
+	if err != nil || config == nil {
+		return err
+	}
+	content := config.FarewellMessage(data)
+	if event.Type == service.EventGuildMemberAdd {
+		content = config.WelcomeMessage(data)
+	}
+	if content == "" || config.ChannelID == "" {
+		return nil
+	}
+	// 使用事件ID发送被动消息
+	channelReplier(config.ChannelID)(&types.MessageToCreate{EventID: event.ID, Content: content})
+	return nil
+}
+
+// HintButtonCallback 成语接龙「提示」按钮，回复一个可以接上的成语
+func HintButtonCallback(interaction *types.Interaction) {
+	hint, err := games.Hint(interactionKey(interaction))
+	content := "提示：" + hint
+	if err != nil {
+		content = err.Error()
+	}
+	interactionReplier(interaction)(&types.MessageToCreate{EventID: interaction.ID, Content: content})
+}
+
+// SurrenderButtonCallback 成语接龙「认输」按钮，结束游戏并公布答案
+func SurrenderButtonCallback(interaction *types.Interaction) {
+	answer, ok := games.Surrender(interactionKey(interaction))
+	content := "好的,游戏结束"
+	switch {
+	case !ok:
+		content = "当前没有进行游戏"
+	case answer != "":
+		content = "好的,游戏结束，可以接上的成语：" + answer
+	}
+	interactionReplier(interaction)(&types.MessageToCreate{EventID: interaction.ID, Content: content})
+}
+
+// handleMessage 优先按指令处理用户消息，其他消息在游戏进行中时作为接龙的成语，否则认为是与用户之间的对话
+func handleMessage(content types.Content, data *types.Message, reply server.Replier) {
+	key := gameKey(data)
+	replyMessage, ok := commands.Dispatch(content.Text(), data, key, reply)
+	if !ok {
+		replyMessage = chat(key, content.PlainText())
+	}
+	builder := types.NewMessageBuilder().Reply(data.ID)
+	// 游戏进行中时附带「提示」「认输」按钮，按钮只能与 markdown 一起发送
+	if utils.ConfigInfo.GameKeyboard && games.InProgress(key) {
+		builder.Markdown(replyMessage).Keyboard(gameKeyboard)
 	} else {
-		replyMessage = InitialOperation(messageContent, data)
+		builder.Content(replyMessage)
 	}
-	_, err = httpClient.PostMessage(ctx, data.ChannelID, &types.MessageToCreate{MsgID: data.ID, Content: replyMessage})
+	msg, err := builder.Build()
 	if err != nil {
-		log.Println("Failed to post message to channel:", data.ChannelID, "with message:", replyMessage, "and error:", err)
+		log.Println("Failed to build reply message:", err)
+		return
 	}
-	return nil
+	reply(msg)
 }
 
-// GameInProgress 游戏还在进行中
-func GameInProgress(messageContent string, data *types.Message) string {
-	// 游戏还在进行中，输入/成语接龙则认为用户希望重新开始游戏
-	if strings.EqualFold(messageContent, "/成语接龙") {
-		server.ResetCurrentIdiom()
-		resetTimer(data)
-		return "好的游戏重新开始，请说出一个四字成语。"
-	}
-	// 游戏还在进行中，输入/quit则退出游戏
-	if strings.EqualFold(messageContent, "/quit") {
-		finishOrNot = false
-		stopTimer()
-		server.ResetCurrentIdiom()
-		return "好的,游戏结束"
-	}
-	// flag表示是否需要结束游戏，词库没有与用户输入匹配的词语则结束游戏
-	interlocking, flag := server.ChengYvInterlocking(messageContent)
-	if flag {
-		finishOrNot = false
-		stopTimer()
+// chat 处理指令之外的消息
+func chat(key string, messageContent string) string {
+	// 词库没有与用户输入匹配的词语时会话会自动结束
+	if interlocking, ok := games.Play(key, messageContent); ok {
 		return interlocking
 	}
-	return interlocking
+	return server.SendMessage(messageContent, utils.ConfigInfo.DashScopeAPIKey)
+}
+
+// channelReplier 回复到子频道
+func channelReplier(channelID string) server.Replier {
+	return func(msg *types.MessageToCreate) {
+		if _, err := httpClient.PostMessage(ctx, channelID, msg); err != nil {
+			log.Println("Failed to post message to channel:", channelID, "with message:", msg.Content, "and error:", err)
+		}
+	}
+}
+
+// directReplier 回复到私信会话
+func directReplier(guildID string) server.Replier {
+	return func(msg *types.MessageToCreate) {
+		dm := &types.DirectMessage{GuildID: guildID}
+		if _, err := httpClient.PostDirectMessage(ctx, dm, msg); err != nil {
+			log.Println("Failed to post direct message to guild:", guildID, "with message:", msg.Content, "and error:", err)
+		}
+	}
 }
 
-// InitialOperation 初始状态下的操作
-func InitialOperation(messageContent string, data *types.Message) string {
-	// 输入指令/成语接龙开始游戏，并将游戏记号位标为正在进行true
-	if strings.EqualFold(messageContent, "/成语接龙") {
-		finishOrNot = true
-		resetTimer(data)
-		return "欢迎来到成语接龙游戏！请说出第一个四字成语"
+// groupReplier 回复到群聊
+func groupReplier(groupOpenID string) server.Replier {
+	return func(msg *types.MessageToCreate) {
+		if _, err := httpClient.PostGroupMessage(ctx, groupOpenID, msg); err != nil {
+			log.Println("Failed to post message to group:", groupOpenID, "with message:", msg.Content, "and error:", err)
+		}
 	}
-	// 因为当前没有任何进度，需要提醒用户当前并没有进行游戏
-	if strings.EqualFold(messageContent, "/quit") {
-		return "当前没有进行游戏"
+}
+
+// c2cReplier 回复到单聊
+func c2cReplier(openID string) server.Replier {
+	return func(msg *types.MessageToCreate) {
+		if _, err := httpClient.PostC2CMessage(ctx, openID, msg); err != nil {
+			log.Println("Failed to post c2c message to user:", openID, "with message:", msg.Content, "and error:", err)
+		}
+	}
+}
+
+// gameKey 获取消息所在的游戏会话key
+func gameKey(data *types.Message) string {
+	switch {
+	case data.GroupOpenID != "":
+		// 群聊以群为单位
+		return games.Key("group", data.GroupOpenID, authorID(data))
+	case data.GuildID == "" && data.ChannelID == "":
+		// 单聊本身就区分了用户
+		return games.Key("c2c", authorID(data), authorID(data))
+	default:
+		// 每个子频道(或用户)拥有独立的游戏会话，私信会话的频道ID本身就区分了用户
+		return games.Key(data.GuildID, data.ChannelID, authorID(data))
 	}
-	// 指令之外的消息，认为是与用户之间的对话
-	reply := server.SendMessage(messageContent, utils.ConfigInfo.DashScopeAPIKey)
-	return reply
 }
 
-// resetTimer 函数用于重置游戏计时器,并在计时器超时时执行相应的结束游戏操作
-func resetTimer(data *types.Message) {
-	if timer != nil {
-		timer.Stop()
+// interactionKey 获取按钮所在的游戏会话key，与 gameKey 的规则一致
+func interactionKey(interaction *types.Interaction) string {
+	switch interaction.ChatType {
+	case types.InteractionChatTypeGroup:
+		return games.Key("group", interaction.GroupOpenID, interaction.GroupMemberOpenID)
+	case types.InteractionChatTypeC2C:
+		return games.Key("c2c", interaction.UserOpenID, interaction.UserOpenID)
+	default:
+		var userID string
+		if interaction.Data != nil && interaction.Data.Resolved != nil {
+			userID = interaction.Data.Resolved.UserID
+		}
+		return games.Key(interaction.GuildID, interaction.ChannelID, userID)
 	}
-	timer = time.NewTimer(60 * time.Second)
-	go func() {
-		<-timer.C
-		// 60秒内没有回答,结束游戏
-		finishOrNot = false
-		server.ResetCurrentIdiom()
-		_, _ = httpClient.PostMessage(ctx, data.ChannelID, &types.MessageToCreate{Content: "60秒内没有回答,游戏结束。"})
-	}()
 }
 
-// stopTimer 函数用于停止当前正在运行的游戏计时器
-func stopTimer() {
-	if timer != nil {
-		timer.Stop()
+// interactionReplier 回复到按钮所在的会话
+func interactionReplier(interaction *types.Interaction) server.Replier {
+	switch interaction.ChatType {
+	case types.InteractionChatTypeGroup:
+		return groupReplier(interaction.GroupOpenID)
+	case types.InteractionChatTypeC2C:
+		return c2cReplier(interaction.UserOpenID)
+	default:
+		return channelReplier(interaction.ChannelID)
+	}
+}
+
+// authorID 获取消息发送者ID，群聊和单聊中使用 openid
+func authorID(data *types.Message) string {
+	if data.Author == nil {
+		return ""
+	}
+	switch {
+	case data.Author.ID != "":
+		return data.Author.ID
+	case data.Author.MemberOpenID != "":
+		return data.Author.MemberOpenID
+	default:
+		return data.Author.UserOpenID
 	}
 }
@@ -0,0 +1,356 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"qqbot/common/types"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// HelpCommand 帮助指令，由 Router 自动注册
+const HelpCommand = "/help"
+
+// ArgType 指令参数类型
+type ArgType int
+
+// 指令参数类型
+const (
+	ArgString   ArgType = iota // 字符串，包含空格时可以使用引号
+	ArgInt                     // 整数
+	ArgDuration                // 时长，如 30s、10m、1h
+	ArgMention                 // @成员，解析为用户ID
+)
+
+// ArgSpec 指令参数说明
+type ArgSpec struct {
+	Name        string
+	Type        ArgType
+	Description string
+	Optional    bool // 可选参数只能出现在必填参数之后
+	Rest        bool // 接收剩余的所有参数，只能是最后一个参数
+}
+
+// Replier 向消息来源回复消息，频道、私信等不同来源使用不同的发送接口
+type Replier func(msg *types.MessageToCreate)
+
+// CommandContext 指令执行的上下文
+type CommandContext struct {
+	Message *types.Message
+	Args    *Args
+	Session string  // 消息所在的会话key，如成语接龙的游戏会话
+	Reply   Replier // 用于指令返回之后的异步回复，如游戏超时提示
+}
+
+// Command 指令
+type Command struct {
+	Name        string // 指令名，以 / 开头
+	Aliases     []string
+	Args        []ArgSpec
+	Description string
+	Usage       string     // 为空时根据 Args 生成
+	Permission  Permission // 为空时所有人可用
+	Handler     func(ctx *CommandContext) string
+}
+
+// usage 返回指令的用法
+func (c *Command) usage() string {
+	if c.Usage != "" {
+		return c.Usage
+	}
+	parts := []string{c.Name}
+	for _, arg := range c.Args {
+		name := arg.Name
+		if arg.Rest {
+			name += "..."
+		}
+		if arg.Optional {
+			parts = append(parts, "["+name+"]")
+		} else {
+			parts = append(parts, "<"+name+">")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// Args 解析后的指令参数
+type Args struct {
+	values map[string]interface{}
+}
+
+// Has 判断参数是否存在
+func (a *Args) Has(name string) bool {
+	_, ok := a.values[name]
+	return ok
+}
+
+// String 获取字符串参数，Rest 参数会以空格拼接
+func (a *Args) String(name string) string {
+	v, _ := a.values[name].(string)
+	return v
+}
+
+// Int 获取整数参数
+func (a *Args) Int(name string) int {
+	v, _ := a.values[name].(int)
+	return v
+}
+
+// Duration 获取时长参数
+func (a *Args) Duration(name string) time.Duration {
+	v, _ := a.values[name].(time.Duration)
+	return v
+}
+
+// User 获取 @成员 参数的用户ID
+func (a *Args) User(name string) string {
+	return a.String(name)
+}
+
+// errUnterminatedQuote 引号没有闭合
+var errUnterminatedQuote = errors.New("引号没有闭合")
+
+// SplitArgs 按空白字符分割参数，单引号或双引号中的内容作为一个参数，双引号中可以使用 \ 转义
+func SplitArgs(s string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		inArg   bool
+		quote   rune
+		escaped bool
+	)
+	for _, r := range s {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case quote == '"' && r == '\\':
+			escaped = true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, errUnterminatedQuote
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// parseArgs 按参数说明解析参数
+func parseArgs(specs []ArgSpec, tokens []string) (*Args, error) {
+	args := &Args{values: make(map[string]interface{})}
+	for i, spec := range specs {
+		if i >= len(tokens) {
+			if spec.Optional {
+				break
+			}
+			return nil, fmt.Errorf("缺少参数 %s", spec.Name)
+		}
+		token := tokens[i]
+		if spec.Rest {
+			token = strings.Join(tokens[i:], " ")
+		}
+		value, err := parseArg(spec, token)
+		if err != nil {
+			return nil, err
+		}
+		args.values[spec.Name] = value
+	}
+	if len(tokens) > len(specs) && (len(specs) == 0 || !specs[len(specs)-1].Rest) {
+		return nil, errors.New("参数过多")
+	}
+	return args, nil
+}
+
+// parseArg 按参数类型转换参数
+func parseArg(spec ArgSpec, token string) (interface{}, error) {
+	switch spec.Type {
+	case ArgInt:
+		v, err := strconv.Atoi(token)
+		if err != nil {
+			return nil, fmt.Errorf("参数 %s 需要是整数", spec.Name)
+		}
+		return v, nil
+	case ArgDuration:
+		v, err := time.ParseDuration(token)
+		if err != nil {
+			return nil, fmt.Errorf("参数 %s 需要是时长，如 30s、10m、1h", spec.Name)
+		}
+		return v, nil
+	case ArgMention:
+		id, ok := strings.CutPrefix(token, "<@")
+		id, _ = strings.CutPrefix(id, "!")
+		id, closed := strings.CutSuffix(id, ">")
+		if !ok || !closed || id == "" {
+			return nil, fmt.Errorf("参数 %s 需要 @成员", spec.Name)
+		}
+		return id, nil
+	default:
+		return token, nil
+	}
+}
+
+// Router 指令路由，按指令名或别名(不区分大小写)分发指令，执行前检查权限
+type Router struct {
+	mu       sync.RWMutex
+	commands []*Command
+	index    map[string]*Command
+	perms    *PermissionChecker
+}
+
+// NewRouter 创建指令路由并注册 /help，perms 为 nil 时只检查身份组
+func NewRouter(perms *PermissionChecker) *Router {
+	if perms == nil {
+		perms = NewPermissionChecker(nil)
+	}
+	r := &Router{index: make(map[string]*Command), perms: perms}
+	_ = r.Register(&Command{
+		Name:        HelpCommand,
+		Aliases:     []string{"/帮助"},
+		Args:        []ArgSpec{{Name: "指令", Type: ArgString, Description: "要查看的指令", Optional: true}},
+		Description: "查看所有指令或指令的详细说明",
+		Handler:     r.help,
+	})
+	return r
+}
+
+// Register 注册指令，指令名和别名不能与已注册的指令重复
+func (r *Router) Register(cmd *Command) error {
+	if !strings.HasPrefix(cmd.Name, "/") || cmd.Handler == nil {
+		return fmt.Errorf("command %q must start with / and have a handler", cmd.Name)
+	}
+	if err := cmd.Permission.validate(); err != nil {
+		return fmt.Errorf("command %s: %w", cmd.Name, err)
+	}
+	for i, arg := range cmd.Args {
+		if arg.Rest && i != len(cmd.Args)-1 {
+			return fmt.Errorf("command %s: rest argument %s must be the last one", cmd.Name, arg.Name)
+		}
+		if i > 0 && cmd.Args[i-1].Optional && !arg.Optional {
+			return fmt.Errorf("command %s: required argument %s after optional ones", cmd.Name, arg.Name)
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := append([]string{cmd.Name}, cmd.Aliases...)
+	for _, name := range names {
+		if _, ok := r.index[strings.ToLower(name)]; ok {
+			return fmt.Errorf("command %s already registered", name)
+		}
+	}
+	for _, name := range names {
+		r.index[strings.ToLower(name)] = cmd
+	}
+	r.commands = append(r.commands, cmd)
+	return nil
+}
+
+// lookup 按指令名或别名查找指令
+func (r *Router) lookup(name string) (*Command, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	cmd, ok := r.index[strings.ToLower(name)]
+	return cmd, ok
+}
+
+// Dispatch 解析并执行指令，content 不是以 / 开头时 handled 返回 false，由调用方按普通消息处理
+func (r *Router) Dispatch(content string, data *types.Message, session string, reply Replier) (result string, handled bool) {
+	content = strings.TrimSpace(content)
+	if !strings.HasPrefix(content, "/") {
+		return "", false
+	}
+	// 与 SplitArgs 使用相同的空白字符分割指令名
+	name, rest := content, ""
+	if i := strings.IndexFunc(content, unicode.IsSpace); i >= 0 {
+		name, rest = content[:i], content[i:]
+	}
+	cmd, ok := r.lookup(name)
+	if !ok {
+		return fmt.Sprintf("未知指令 %s，输入 %s 查看所有指令", name, HelpCommand), true
+	}
+	// 先检查权限再解析参数，没有权限的成员看不到参数格式
+	if !r.perms.Allowed(cmd.Name, cmd.Permission, data) {
+		return RefusalMessage, true
+	}
+	tokens, err := SplitArgs(rest)
+	if err != nil {
+		return fmt.Sprintf("%v，用法：%s", err, cmd.usage()), true
+	}
+	args, err := parseArgs(cmd.Args, tokens)
+	if err != nil {
+		return fmt.Sprintf("%v，用法：%s", err, cmd.usage()), true
+	}
+	return cmd.Handler(&CommandContext{Message: data, Args: args, Session: session, Reply: reply}), true
+}
+
+// help 生成所有指令的列表或单个指令的详细说明
+func (r *Router) help(ctx *CommandContext) string {
+	if ctx.Args.Has("指令") {
+		name := ctx.Args.String("指令")
+		if !strings.HasPrefix(name, "/") {
+			name = "/" + name
+		}
+		cmd, ok := r.lookup(name)
+		if !ok {
+			return fmt.Sprintf("未知指令 %s，输入 %s 查看所有指令", name, HelpCommand)
+		}
+		return commandHelp(cmd)
+	}
+	r.mu.RLock()
+	commands := append([]*Command(nil), r.commands...)
+	r.mu.RUnlock()
+	// /help 放在最后
+	sort.SliceStable(commands, func(i, j int) bool {
+		return commands[i].Name != HelpCommand && commands[j].Name == HelpCommand
+	})
+	var b strings.Builder
+	b.WriteString("可用指令：")
+	for _, cmd := range commands {
+		fmt.Fprintf(&b, "\n%s  %s", cmd.usage(), cmd.Description)
+	}
+	fmt.Fprintf(&b, "\n输入 %s <指令> 查看指令的详细说明", HelpCommand)
+	return b.String()
+}
+
+// commandHelp 单个指令的详细说明
+func commandHelp(cmd *Command) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n%s", cmd.usage(), cmd.Description)
+	if len(cmd.Aliases) > 0 {
+		fmt.Fprintf(&b, "\n别名：%s", strings.Join(cmd.Aliases, "、"))
+	}
+	if len(cmd.Args) > 0 {
+		b.WriteString("\n参数：")
+		for _, arg := range cmd.Args {
+			fmt.Fprintf(&b, "\n  %s  %s", arg.Name, arg.Description)
+			if arg.Optional {
+				b.WriteString("(可选)")
+			}
+		}
+	}
+	if len(cmd.Permission.Roles) > 0 {
+		b.WriteString("\n仅限指定身份组或被授权的成员使用")
+	}
+	return b.String()
+}
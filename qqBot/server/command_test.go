@@ -0,0 +1,108 @@
+package server
+
+import (
+	"qqbot/common/types"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitArgs(t *testing.T) {
+	cases := map[string][]string{
+		``:                       nil,
+		`  a  b `:                {"a", "b"},
+		`"hello world" x`:        {"hello world", "x"},
+		`'it"s' "say \"hi\"" ""`: {`it"s`, `say "hi"`, ""},
+		`a"b c"d`:                {"ab cd"},
+	}
+	for input, want := range cases {
+		got, err := SplitArgs(input)
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Fatalf("split %q: got %q, err %v", input, got, err)
+		}
+	}
+	for _, input := range []string{`"open`, `'open`, `"a\`} {
+		if _, err := SplitArgs(input); err != errUnterminatedQuote {
+			t.Fatalf("split %q: expect unterminated quote, got %v", input, err)
+		}
+	}
+}
+
+func TestRouter(t *testing.T) {
+	router := NewRouter(nil)
+	var got *Args
+	echo := &Command{
+		Name:    "/echo",
+		Aliases: []string{"/回声"},
+		Args: []ArgSpec{
+			{Name: "times", Type: ArgInt, Description: "次数"},
+			{Name: "wait", Type: ArgDuration, Description: "间隔", Optional: true},
+			{Name: "text", Type: ArgString, Description: "内容", Optional: true, Rest: true},
+		},
+		Description: "复读",
+		Handler: func(ctx *CommandContext) string {
+			got = ctx.Args
+			return "ok"
+		},
+	}
+	if err := router.Register(echo); err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+
+	t.Run("test register validation", func(t *testing.T) {
+		for _, cmd := range []*Command{
+			{Name: "echo", Handler: echo.Handler},
+			{Name: "/ECHO", Handler: echo.Handler},
+			{Name: "/x", Aliases: []string{"/回声"}, Handler: echo.Handler},
+			{Name: "/x", Args: []ArgSpec{{Name: "a", Rest: true}, {Name: "b"}}, Handler: echo.Handler},
+			{Name: "/x", Args: []ArgSpec{{Name: "a", Optional: true}, {Name: "b"}}, Handler: echo.Handler},
+		} {
+			if err := router.Register(cmd); err == nil {
+				t.Fatalf("register %+v should fail", cmd)
+			}
+		}
+	})
+
+	t.Run("test dispatch with typed args", func(t *testing.T) {
+		reply, ok := router.Dispatch(` /回声 3 1m "hello world" again`, &types.Message{}, "", nil)
+		if !ok || reply != "ok" || got.Int("times") != 3 || got.Duration("wait") != time.Minute || got.String("text") != "hello world again" {
+			t.Fatalf("unexpected dispatch %q %v, args %+v", reply, ok, got)
+		}
+		if reply, _ := router.Dispatch("/echo\n2\t1s", &types.Message{}, "", nil); reply != "ok" || got.Int("times") != 2 || got.Duration("wait") != time.Second {
+			t.Fatalf("command name should be split on any whitespace, got %q, args %+v", reply, got)
+		}
+		if _, ok = router.Dispatch("/Echo 1", &types.Message{}, "", nil); !ok || got.Has("wait") {
+			t.Fatalf("optional args should be absent, args %+v", got)
+		}
+		if _, ok = router.Dispatch("锦上添花", &types.Message{}, "", nil); ok {
+			t.Fatalf("plain message should not be handled")
+		}
+	})
+
+	t.Run("test argument errors", func(t *testing.T) {
+		cases := map[string]string{
+			"/echo":        "缺少参数 times，用法：/echo <times> [wait] [text...]",
+			"/echo x":      "参数 times 需要是整数，用法：/echo <times> [wait] [text...]",
+			`/echo 1 1m "`: "引号没有闭合，用法：/echo <times> [wait] [text...]",
+			"/unknown":     "未知指令 /unknown，输入 /help 查看所有指令",
+		}
+		for input, want := range cases {
+			if reply, _ := router.Dispatch(input, &types.Message{}, "", nil); reply != want {
+				t.Fatalf("dispatch %q: got %q", input, reply)
+			}
+		}
+	})
+
+	t.Run("test help", func(t *testing.T) {
+		reply, _ := router.Dispatch("/help", &types.Message{}, "", nil)
+		lines := strings.Split(reply, "\n")
+		if len(lines) != 4 || lines[1] != "/echo <times> [wait] [text...]  复读" || !strings.HasPrefix(lines[2], "/help [指令]") {
+			t.Fatalf("unexpected help %q", reply)
+		}
+		reply, _ = router.Dispatch("/帮助 回声", &types.Message{}, "", nil)
+		if !strings.HasPrefix(reply, "/echo <times> [wait] [text...]\n复读\n别名：/回声\n参数：") {
+			t.Fatalf("unexpected command help %q", reply)
+		}
+	})
+}
@@ -0,0 +1,52 @@
+package server
+
+import "qqbot/common/types"
+
+// 成语接龙游戏的指令
+const (
+	IdiomCommand = "/成语接龙"
+	QuitCommand  = "/quit"
+)
+
+// NewGameCommands 创建成语接龙游戏的指令，游戏会话为 CommandContext.Session
+func NewGameCommands(games *GameManager) []*Command {
+	return []*Command{
+		{
+			Name:        IdiomCommand,
+			Aliases:     []string{"/idiom"},
+			Description: "开始游戏，当前正在进行游戏则重新开始",
+			Handler: func(ctx *CommandContext) string {
+				// 游戏还在进行中，输入/成语接龙则认为用户希望重新开始游戏
+				restart := games.InProgress(ctx.Session)
+				games.Start(ctx.Session, gameExpired(ctx.Reply))
+				if restart {
+					return "好的游戏重新开始，请说出一个四字成语。"
+				}
+				return "欢迎来到成语接龙游戏！请说出第一个四字成语"
+			},
+		},
+		{
+			Name:        QuitCommand,
+			Aliases:     []string{"/退出"},
+			Description: "退出游戏",
+			Handler: func(ctx *CommandContext) string {
+				if games.Finish(ctx.Session) {
+					return "好的,游戏结束"
+				}
+				// 因为当前没有任何进度，需要提醒用户当前并没有进行游戏
+				return "当前没有进行游戏"
+			},
+		},
+	}
+}
+
+// gameExpired 返回游戏超时后的回调,向发起游戏的会话发送结束提示
+func gameExpired(reply Replier) func() {
+	return func() {
+		if reply == nil {
+			return
+		}
+		// 60秒内没有回答,结束游戏
+		reply(&types.MessageToCreate{Content: "60秒内没有回答,游戏结束。"})
+	}
+}
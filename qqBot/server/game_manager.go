@@ -0,0 +1,175 @@
+package server
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// gameSession 单个频道(或用户)的游戏会话,持有独立的游戏状态与超时计时器
+type gameSession struct {
+	key      string
+	game     *IdiomGame
+	timer    *time.Timer
+	gen      uint64 // 计时器代数,每次重置计时器递增,用于丢弃过期的超时回调
+	onExpire func()
+}
+
+// GameManager 并发安全的成语接龙会话管理器,按 guild/channel(可选 user)区分会话
+type GameManager struct {
+	mu       sync.Mutex
+	sessions map[string]*gameSession
+	timeout  time.Duration
+	perUser  bool
+}
+
+// NewGameManager 创建游戏会话管理器,timeout 为无人回答时自动结束游戏的时间,
+// perUser 为 true 时同一子频道内的每个用户各自拥有独立的游戏
+func NewGameManager(timeout time.Duration, perUser bool) *GameManager {
+	return &GameManager{
+		sessions: make(map[string]*gameSession),
+		timeout:  timeout,
+		perUser:  perUser,
+	}
+}
+
+// Key 根据消息来源生成会话 key
+func (m *GameManager) Key(guildID, channelID, userID string) string {
+	parts := []string{guildID, channelID}
+	if m.perUser {
+		parts = append(parts, userID)
+	}
+	return strings.Join(parts, "/")
+}
+
+// InProgress 判断 key 对应的会话是否有游戏正在进行
+func (m *GameManager) InProgress(key string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.sessions[key]
+	return ok
+}
+
+// Start 开始(或重新开始)一局游戏,超时未回答时会调用 onExpire
+func (m *GameManager) Start(key string, onExpire func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok := m.sessions[key]; ok {
+		m.removeLocked(s)
+	}
+	s := &gameSession{key: key, game: &IdiomGame{}, onExpire: onExpire}
+	m.sessions[key] = s
+	m.resetTimerLocked(s)
+}
+
+// Play 在 key 对应的会话中进行一轮接龙,会话不存在时 ok 返回 false
+func (m *GameManager) Play(key string, idiom string) (reply string, ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[key]
+	if !ok {
+		return "", false
+	}
+	m.resetTimerLocked(s)
+	reply, finished := s.game.ChengYvInterlocking(idiom)
+	// 用户获胜,游戏结束
+	if finished {
+		m.removeLocked(s)
+	}
+	return reply, true
+}
+
+// Finish 结束 key 对应的游戏,返回结束前游戏是否在进行中
+func (m *GameManager) Finish(key string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[key]
+	if ok {
+		m.removeLocked(s)
+	}
+	return ok
+}
+
+// Hint 返回 key 对应游戏的提示成语，会话不存在时返回 ErrNoGame，其他错误见 IdiomGame.Hint，
+// 提示不算作回答，不会重置计时器
+func (m *GameManager) Hint(key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[key]
+	if !ok {
+		return "", ErrNoGame
+	}
+	return s.game.Hint()
+}
+
+// Surrender 认输并结束 key 对应的游戏，返回一个可以接上的成语作为答案，会话不存在时 ok 返回 false
+func (m *GameManager) Surrender(key string) (answer string, ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[key]
+	if !ok {
+		return "", false
+	}
+	answer, _ = s.game.Hint()
+	m.removeLocked(s)
+	return answer, true
+}
+
+// FinishIn 结束频道(channelID 不为空时为子频道)下的所有游戏，返回结束的游戏数，
+// 用于机器人退出频道或子频道被删除时清理会话
+func (m *GameManager) FinishIn(guildID, channelID string) int {
+	prefix := guildID + "/"
+	if channelID != "" {
+		prefix += channelID
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int
+	for key, s := range m.sessions {
+		// 不按用户区分时 key 即为 guild/channel，需要整体匹配
+		if channelID != "" && key != prefix && !strings.HasPrefix(key, prefix+"/") {
+			continue
+		}
+		if channelID == "" && !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		m.removeLocked(s)
+		n++
+	}
+	return n
+}
+
+// resetTimerLocked 重置会话的超时计时器,调用方需持有 m.mu
+func (m *GameManager) resetTimerLocked(s *gameSession) {
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	s.gen++
+	gen := s.gen
+	s.timer = time.AfterFunc(m.timeout, func() {
+		m.expire(s, gen)
+	})
+}
+
+// expire 计时器超时回调,会话在此期间被重置或结束时直接忽略
+func (m *GameManager) expire(s *gameSession, gen uint64) {
+	m.mu.Lock()
+	if m.sessions[s.key] != s || s.gen != gen {
+		m.mu.Unlock()
+		return
+	}
+	m.removeLocked(s)
+	m.mu.Unlock()
+	if s.onExpire != nil {
+		s.onExpire()
+	}
+}
+
+// removeLocked 移除会话并停止计时器,调用方需持有 m.mu
+func (m *GameManager) removeLocked(s *gameSession) {
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	s.gen++
+	delete(m.sessions, s.key)
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGameManager(t *testing.T) {
+	idiomMap = map[string][]string{
+		"花": {"花好月圆"},
+		"圆": {"圆木警枕"},
+	}
+
+	t.Run("test sessions of different channels are isolated", func(t *testing.T) {
+		m := NewGameManager(time.Minute, false)
+		keyA := m.Key("guild", "channelA", "user1")
+		keyB := m.Key("guild", "channelB", "user1")
+		m.Start(keyA, nil)
+		m.Start(keyB, nil)
+
+		if reply, ok := m.Play(keyA, "锦上添花"); !ok || reply != "花好月圆" {
+			t.Fatalf("unexpected reply %q, ok %v", reply, ok)
+		}
+		// channelB 的游戏不受 channelA 的进度影响
+		if reply, _ := m.Play(keyB, "锦上添花"); reply != "花好月圆" {
+			t.Fatalf("unexpected reply %q", reply)
+		}
+		// channelA 退出不会结束 channelB 的游戏
+		m.Finish(keyA)
+		if m.InProgress(keyA) || !m.InProgress(keyB) {
+			t.Fatalf("quit in channelA should only end its own game")
+		}
+	})
+
+	t.Run("test per user key", func(t *testing.T) {
+		shared := NewGameManager(time.Minute, false)
+		if shared.Key("g", "c", "u1") != shared.Key("g", "c", "u2") {
+			t.Fatalf("users in the same channel should share a game")
+		}
+		perUser := NewGameManager(time.Minute, true)
+		if perUser.Key("g", "c", "u1") == perUser.Key("g", "c", "u2") {
+			t.Fatalf("users should have their own game")
+		}
+	})
+
+	t.Run("test finish games in guild or channel", func(t *testing.T) {
+		m := NewGameManager(time.Minute, true)
+		for _, key := range []string{m.Key("g1", "c1", "u1"), m.Key("g1", "c1", "u2"), m.Key("g1", "c10", "u1"), m.Key("g2", "c1", "u1")} {
+			m.Start(key, nil)
+		}
+		if n := m.FinishIn("g1", "c1"); n != 2 || !m.InProgress(m.Key("g1", "c10", "u1")) {
+			t.Fatalf("only games in g1/c1 should be finished, finished %d", n)
+		}
+		if n := m.FinishIn("g1", ""); n != 1 || !m.InProgress(m.Key("g2", "c1", "u1")) {
+			t.Fatalf("only games in g1 should be finished, finished %d", n)
+		}
+	})
+
+	t.Run("test hint and surrender", func(t *testing.T) {
+		m := NewGameManager(time.Minute, false)
+		if _, err := m.Hint("k"); err != ErrNoGame {
+			t.Fatalf("hint should fail without game, got %v", err)
+		}
+		m.Start("k", nil)
+		if hint, err := m.Hint("k"); err != ErrNoIdiomYet {
+			t.Fatalf("no hint before the first idiom, got %q, %v", hint, err)
+		}
+		m.Play("k", "锦上添花")
+		if hint, err := m.Hint("k"); err != nil || hint != "圆木警枕" {
+			t.Fatalf("unexpected hint %q, %v", hint, err)
+		}
+		if _, err := (&IdiomGame{currentIdiom: "一马当先"}).Hint(); err != ErrNoNextIdiom {
+			t.Fatalf("expect no next idiom, got %v", err)
+		}
+		if answer, ok := m.Surrender("k"); !ok || answer != "圆木警枕" || m.InProgress("k") {
+			t.Fatalf("surrender should finish the game, answer %q", answer)
+		}
+	})
+
+	t.Run("test game finished when user wins", func(t *testing.T) {
+		m := NewGameManager(time.Minute, false)
+		m.Start("k", nil)
+		if _, ok := m.Play("k", "一马当先"); !ok {
+			t.Fatalf("game should be in progress")
+		}
+		if m.InProgress("k") {
+			t.Fatalf("game should be finished when no idiom can follow")
+		}
+	})
+
+	t.Run("test session expired", func(t *testing.T) {
+		m := NewGameManager(20*time.Millisecond, false)
+		expired := make(chan struct{})
+		m.Start("k", func() { close(expired) })
+		select {
+		case <-expired:
+		case <-time.After(time.Second):
+			t.Fatalf("onExpire not called")
+		}
+		if m.InProgress("k") {
+			t.Fatalf("expired game should be removed")
+		}
+	})
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"math/rand"
 	"os"
@@ -12,10 +13,13 @@ import (
 
 const dataTxtPath string = "Address of your corpus file"
 
-var (
-	idiomMap     map[string][]string
+var idiomMap map[string][]string
+
+// IdiomGame 一局成语接龙游戏的状态,每个会话各自持有,互不影响
+type IdiomGame struct {
+	// currentIdiom 记录机器人上次回答的成语
 	currentIdiom string
-)
+}
 
 // NewIdiomMap 初始化词库
 func NewIdiomMap() {
@@ -54,7 +58,7 @@ func NewIdiomMap() {
 }
 
 // ChengYvInterlocking 成语接龙游戏进行逻辑
-func ChengYvInterlocking(idiom string) (string, bool) {
+func (g *IdiomGame) ChengYvInterlocking(idiom string) (string, bool) {
 	//去除空格
 	idiom = strings.TrimSpace(idiom)
 	if idiom == "" {
@@ -70,8 +74,8 @@ func ChengYvInterlocking(idiom string) (string, bool) {
 		return "您输入的不是四字词语请重新输入", false
 	}
 	//判断是否是一句新的开局游戏，如果不是检查用户输入是否正确
-	if currentIdiom != "" {
-		flag := checkIdiom(currentIdiom, idiom)
+	if g.currentIdiom != "" {
+		flag := checkIdiom(g.currentIdiom, idiom)
 		if !flag {
 			return "您输入的成语不符合游戏规则,请重新输入。", false
 		}
@@ -80,16 +84,29 @@ func ChengYvInterlocking(idiom string) (string, bool) {
 	nextIdiom := FindNextIdiom(idiom)
 	//没有找到，则将记录清空并返回游戏技术标志true
 	if nextIdiom == "" {
-		currentIdiom = ""
+		g.currentIdiom = ""
 		return fmt.Sprintf("没有找到可以接上'%s'的成语，恭喜你获得游戏胜利。\n", idiom), true
 	}
-	currentIdiom = nextIdiom
+	g.currentIdiom = nextIdiom
 	return nextIdiom, false
 }
 
-// ResetCurrentIdiom 清空机器上次回答记录
-func ResetCurrentIdiom() {
-	currentIdiom = ""
+// 获取提示失败的原因
+var (
+	ErrNoGame      = errors.New("当前没有进行游戏")
+	ErrNoIdiomYet  = errors.New("请先说出一个四字成语")
+	ErrNoNextIdiom = errors.New("词库中也没有可以接上的成语")
+)
+
+// Hint 返回一个可以接上机器人上次回答的成语，还没有开始接龙时返回 ErrNoIdiomYet，词库中没有时返回 ErrNoNextIdiom
+func (g *IdiomGame) Hint() (string, error) {
+	if g.currentIdiom == "" {
+		return "", ErrNoIdiomYet
+	}
+	if next := FindNextIdiom(g.currentIdiom); next != "" {
+		return next, nil
+	}
+	return "", ErrNoNextIdiom
 }
 
 // checkIdiom 判断用户回答是否正确
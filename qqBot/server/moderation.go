@@ -0,0 +1,68 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"qqbot/common/types"
+	"strings"
+	"time"
+)
+
+// MuteCommand 禁言指令，用法：/mute @成员 10m
+const MuteCommand = "/mute"
+
+// MemberMuter 禁言成员，service.HttpClient 实现了该接口
+type MemberMuter interface {
+	MuteMember(ctx context.Context, guildID, userID string, mute *types.UpdateGuildMute) error
+}
+
+// NewMuteCommand 创建禁言指令，禁言作用于整个频道，需要管理员、频道主身份组或频道授权
+func NewMuteCommand(muter MemberMuter) *Command {
+	return &Command{
+		Name:    MuteCommand,
+		Aliases: []string{"/禁言"},
+		Args: []ArgSpec{
+			{Name: "成员", Type: ArgMention, Description: "@要禁言的成员"},
+			{Name: "时长", Type: ArgDuration, Description: "禁言时长，如 30s、10m、1h"},
+		},
+		Description: "禁言成员",
+		Permission:  PermissionAdmin,
+		Handler: func(ctx *CommandContext) string {
+			userID, d := ctx.Args.User("成员"), ctx.Args.Duration("时长")
+			if d < time.Second {
+				return "禁言时长不能少于1秒"
+			}
+			guildID := ctx.Message.GuildID
+			if err := muter.MuteMember(context.Background(), guildID, userID, types.MuteFor(d)); err != nil {
+				log.Println("Failed to mute member:", userID, "in guild:", guildID, "with error:", err)
+				return "禁言失败，请检查机器人是否拥有管理权限"
+			}
+			return fmt.Sprintf("已禁言 <@!%s> %s", userID, formatDuration(d))
+		},
+	}
+}
+
+// formatDuration 将时长格式化为中文，如 1小时30分钟，不足1秒的部分舍去
+func formatDuration(d time.Duration) string {
+	units := []struct {
+		d    time.Duration
+		name string
+	}{
+		{24 * time.Hour, "天"},
+		{time.Hour, "小时"},
+		{time.Minute, "分钟"},
+		{time.Second, "秒"},
+	}
+	var b strings.Builder
+	for _, u := range units {
+		if n := d / u.d; n > 0 {
+			fmt.Fprintf(&b, "%d%s", n, u.name)
+			d -= n * u.d
+		}
+	}
+	if b.Len() == 0 {
+		return "0秒"
+	}
+	return b.String()
+}
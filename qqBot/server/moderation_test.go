@@ -0,0 +1,73 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"qqbot/common/types"
+	"testing"
+	"time"
+)
+
+// fakeMuter 记录禁言请求
+type fakeMuter struct {
+	userID string
+	mute   *types.UpdateGuildMute
+	err    error
+}
+
+func (f *fakeMuter) MuteMember(ctx context.Context, guildID, userID string, mute *types.UpdateGuildMute) error {
+	f.userID, f.mute = userID, mute
+	return f.err
+}
+
+func TestMuteCommand(t *testing.T) {
+	muter := &fakeMuter{}
+	router := NewRouter(nil)
+	if err := router.Register(NewMuteCommand(muter)); err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+	admin := &types.Message{GuildID: "g1", Author: &types.User{ID: "a1"}, Member: &types.Member{Roles: []string{types.RoleIDAdmin}}}
+
+	t.Run("test member muted", func(t *testing.T) {
+		reply, _ := router.Dispatch("/mute <@!u1> 10m", admin, "", nil)
+		if reply != "已禁言 <@!u1> 10分钟" || muter.userID != "u1" || muter.mute.MuteSeconds != "600" {
+			t.Fatalf("unexpected reply %q, muted %s %+v", reply, muter.userID, muter.mute)
+		}
+	})
+
+	t.Run("test duration formatted in chinese", func(t *testing.T) {
+		cases := map[time.Duration]string{
+			30 * time.Second:                     "30秒",
+			90 * time.Minute:                     "1小时30分钟",
+			25*time.Hour + 1500*time.Millisecond: "1天1小时1秒",
+			500 * time.Millisecond:               "0秒",
+		}
+		for d, want := range cases {
+			if got := formatDuration(d); got != want {
+				t.Fatalf("format %s: got %q, want %q", d, got, want)
+			}
+		}
+	})
+
+	t.Run("test invalid args and failures", func(t *testing.T) {
+		if reply, _ := router.Dispatch("/mute <@!u1> 0s", admin, "", nil); reply != "禁言时长不能少于1秒" {
+			t.Fatalf("unexpected reply %q", reply)
+		}
+		if reply, _ := router.Dispatch("/mute u1 10m", admin, "", nil); reply != "参数 成员 需要 @成员，用法：/mute <成员> <时长>" {
+			t.Fatalf("unexpected reply %q", reply)
+		}
+		muter.err = errors.New("forbidden")
+		defer func() { muter.err = nil }()
+		if reply, _ := router.Dispatch("/禁言 <@u1> 1h", admin, "", nil); reply != "禁言失败，请检查机器人是否拥有管理权限" || muter.mute.MuteSeconds != "3600" {
+			t.Fatalf("unexpected reply %q", reply)
+		}
+	})
+
+	t.Run("test member without role refused", func(t *testing.T) {
+		member := &types.Message{GuildID: "g1", Author: &types.User{ID: "u2"}, Member: &types.Member{Roles: []string{types.RoleIDAll}}}
+		muter.userID = ""
+		if reply, _ := router.Dispatch("/mute <@!u1> 10m", member, "", nil); reply != RefusalMessage || muter.userID != "" {
+			t.Fatalf("unexpected reply %q", reply)
+		}
+	})
+}
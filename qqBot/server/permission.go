@@ -0,0 +1,81 @@
+package server
+
+import (
+	"errors"
+	"log"
+	"qqbot/common/types"
+)
+
+// RefusalMessage 没有权限使用指令时的统一回复
+const RefusalMessage = "抱歉，你没有权限使用该指令"
+
+// Permission 指令需要的权限，成员拥有任意一个身份组或者在频道内被授权即可使用，Roles 为空时所有人可用
+type Permission struct {
+	// 身份组ID，可以是系统身份组如 types.RoleIDAdmin，也可以是自定义身份组ID。
+	// 不支持子频道管理员 types.RoleIDChannelAdmin：成员的身份组中不包含管理的是哪个子频道，
+	// 需要时通过频道授权单独开放
+	Roles []string
+}
+
+// errChannelAdminRole 指令权限中使用了不支持的子频道管理员身份组
+var errChannelAdminRole = errors.New("channel admin role is not supported in command permissions")
+
+// validate 检查权限中是否使用了不支持的身份组
+func (p Permission) validate() error {
+	for _, role := range p.Roles {
+		if role == types.RoleIDChannelAdmin {
+			return errChannelAdminRole
+		}
+	}
+	return nil
+}
+
+// 常用的指令权限
+var (
+	PermissionEveryone = Permission{}
+	PermissionOwner    = Permission{Roles: []string{types.RoleIDOwner}}
+	PermissionAdmin    = Permission{Roles: []string{types.RoleIDOwner, types.RoleIDAdmin}}
+)
+
+// GrantStore 频道内的指令授权存储，如 model.CommandGrantStore
+type GrantStore interface {
+	// Granted 判断用户或其身份组是否被授权在频道内使用指令
+	Granted(guildID, command, userID string, roles []string) (bool, error)
+}
+
+// PermissionChecker 根据成员的身份组和频道授权检查指令权限
+type PermissionChecker struct {
+	grants GrantStore
+}
+
+// NewPermissionChecker 创建权限检查器，grants 为 nil 时只检查身份组
+func NewPermissionChecker(grants GrantStore) *PermissionChecker {
+	return &PermissionChecker{grants: grants}
+}
+
+// Allowed 判断消息发送者是否可以使用指令，私信、群聊等没有频道成员信息的场景只能使用所有人可用的指令
+func (c *PermissionChecker) Allowed(command string, perm Permission, data *types.Message) bool {
+	if len(perm.Roles) == 0 {
+		return true
+	}
+	if data.Member == nil || data.Author == nil || data.GuildID == "" || data.DirectMessage {
+		return false
+	}
+	for _, role := range data.Member.Roles {
+		for _, required := range perm.Roles {
+			// 子频道管理员不区分子频道，不能据此授予权限
+			if role == required && role != types.RoleIDChannelAdmin {
+				return true
+			}
+		}
+	}
+	if c.grants == nil {
+		return false
+	}
+	granted, err := c.grants.Granted(data.GuildID, command, data.Author.ID, data.Member.Roles)
+	if err != nil {
+		log.Printf("[permission] check grant of %s for %s failed, %v", command, data.Author.ID, err)
+		return false
+	}
+	return granted
+}
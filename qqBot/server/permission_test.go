@@ -0,0 +1,73 @@
+package server
+
+import (
+	"errors"
+	"qqbot/common/types"
+	"testing"
+)
+
+// fakeGrants 测试用的频道授权，key 为 guild/command/userID 或 guild/command/roleID
+type fakeGrants struct {
+	grants map[string]bool
+	err    error
+}
+
+func (f *fakeGrants) Granted(guildID, command, userID string, roles []string) (bool, error) {
+	if f.err != nil {
+		return false, f.err
+	}
+	for _, id := range append([]string{userID}, roles...) {
+		if f.grants[guildID+"/"+command+"/"+id] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestPermission(t *testing.T) {
+	message := func(roles ...string) *types.Message {
+		return &types.Message{GuildID: "g1", Author: &types.User{ID: "u1"}, Member: &types.Member{Roles: roles}}
+	}
+	grants := &fakeGrants{grants: map[string]bool{"g1//mute/u1": true, "g1//kick/100": true}}
+	checker := NewPermissionChecker(grants)
+
+	t.Run("test required roles", func(t *testing.T) {
+		if !checker.Allowed("/kick", PermissionEveryone, &types.Message{}) {
+			t.Fatalf("everyone should be allowed")
+		}
+		if !checker.Allowed("/kick", PermissionAdmin, message(types.RoleIDAll, types.RoleIDAdmin)) {
+			t.Fatalf("admin should be allowed")
+		}
+		// 子频道管理员只管理自己的子频道，不能据此授予权限
+		if checker.Allowed("/kick", PermissionAdmin, message(types.RoleIDChannelAdmin)) {
+			t.Fatalf("channel admin should not be allowed to use admin commands")
+		}
+		channelAdmin := Permission{Roles: []string{types.RoleIDChannelAdmin}}
+		if checker.Allowed("/kick", channelAdmin, message(types.RoleIDChannelAdmin)) {
+			t.Fatalf("channel admin role should never be matched")
+		}
+		if err := NewRouter(nil).Register(&Command{Name: "/kick", Permission: channelAdmin, Handler: func(*CommandContext) string { return "" }}); err == nil {
+			t.Fatalf("command with channel admin permission should be rejected")
+		}
+		if !checker.Allowed("/kick", Permission{Roles: []string{"100"}}, message("100")) {
+			t.Fatalf("custom role should be allowed")
+		}
+	})
+
+	t.Run("test guild grants", func(t *testing.T) {
+		if !checker.Allowed("/mute", PermissionAdmin, message(types.RoleIDAll)) {
+			t.Fatalf("granted user should be allowed")
+		}
+		if !checker.Allowed("/kick", PermissionOwner, message("100")) {
+			t.Fatalf("granted role should be allowed")
+		}
+		dm := message(types.RoleIDAll)
+		dm.DirectMessage = true
+		if checker.Allowed("/mute", PermissionAdmin, dm) {
+			t.Fatalf("grants should not apply to direct messages")
+		}
+		if NewPermissionChecker(&fakeGrants{err: errors.New("db down")}).Allowed("/mute", PermissionAdmin, message()) {
+			t.Fatalf("grant errors should deny")
+		}
+	})
+}
@@ -11,9 +11,10 @@ func TestServer(t *testing.T) {
 	t.Run(
 		"test idiom_solitaire", func(t *testing.T) {
 			server.NewIdiomMap()
+			game := &server.IdiomGame{}
 			idiomTestExamples := []string{"锦上添花", "153锦上天花", "圆润", "   "}
 			for _, testExample := range idiomTestExamples {
-				nextRecover, _ := server.ChengYvInterlocking(testExample)
+				nextRecover, _ := game.ChengYvInterlocking(testExample)
 				log.Println(nextRecover)
 			}
 		},
@@ -12,8 +12,14 @@ const configFilePath = "Your configuration file path"
 type Config struct {
 	AppID           uint64 `yaml:"appid"`
 	Token           string `yaml:"token"`
+	Secret          string `yaml:"secret"`      // 机器人密钥，配置后使用 QQBot access token 鉴权，同时用于回调签名校验
+	WebhookAddr     string `yaml:"webhookAddr"` // 配置后以 HTTP 回调模式接收事件，如 :8080
+	Sandbox         bool   `yaml:"sandbox"`     // 使用沙箱环境
+	OpenAPIURL      string `yaml:"openAPIURL"`  // 自定义 OpenAPI 地址，优先级高于 sandbox
 	DashScopeAPIKey string `yaml:"dashScopeAPIKey"`
 	Mysql           string `yaml:"mysql"`
+	GamePerUser     bool   `yaml:"gamePerUser"`  // 为 true 时同一子频道内每个用户拥有独立的成语接龙游戏
+	GameKeyboard    bool   `yaml:"gameKeyboard"` // 为 true 时成语接龙以 markdown 回复并附带「提示」「认输」按钮，需要开通 markdown 和按钮权限
 }
 
 var (